@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/doutorfinancas/go-mad/core"
@@ -24,6 +25,8 @@ var rootCmd = &cobra.Command{
 	Short: "MySQL Anonymized Dump",
 	Long: `A full fledged anonymized dump facility that allows some compatibility
 				with mysql original flags for mysqldump`,
+	// databases are given as arguments, which cobra would otherwise take for unknown subcommands
+	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		if getVersion {
 			fmt.Printf(
@@ -36,73 +39,66 @@ var rootCmd = &cobra.Command{
 			os.Exit(0)
 		}
 
-		logger, _ := zap.NewProduction()
+		logger := newLogger()
+		defer syncLogger(logger)
 
-		if debug {
-			logger, _ = zap.NewDevelopment()
-		}
+		checkDatabaseArgs(logger, args)
 
-		defer func(logger *zap.Logger) {
-			err := logger.Sync()
-			if err != nil &&
-				(!strings.Contains(err.Error(), "invalid argument") && !strings.Contains(
-					err.Error(),
-					"inappropriate ioctl for device",
-				)) {
-				logger.Fatal(
-					err.Error(),
-					zap.String("step", "logger finalization"),
-				)
-			}
-		}(logger) // flushes buffer, if any
+		if resume && checkpointPath == "" {
+			checkpointPath = outputPath + ".checkpoint"
+		}
 
-		if len(args) != 1 {
+		if checkpointPath != "" && outputPath == "stdout" {
 			logger.Fatal(
-				"database is required",
+				"checkpoints require an output file",
 				zap.String("step", "arguments initialization"),
 			)
 		}
 
-		if pwd == "" && cmd.PersistentFlags().Changed("password") {
-			validate := func(input string) error {
-				if len(input) < 1 {
-					logger.Fatal(
-						"password flag is set, so it is required",
-						zap.String("step", "arguments initialization"),
-					)
-				}
-				return nil
-			}
+		if outputDir != "" && (outputPath != "stdout" || checkpointPath != "") {
+			logger.Fatal(
+				"--output-dir cannot be used with --output or checkpoints",
+				zap.String("step", "arguments initialization"),
+			)
+		}
 
-			prompt := promptui.Prompt{
-				Label:    "Password",
-				Validate: validate,
-				Mask:     '*',
-			}
+		if compress == "" && outputPath != "stdout" {
+			compress = core.CompressionFromPath(outputPath)
+		}
 
-			res, err := prompt.Run()
-			if err != nil {
-				logger.Fatal(
-					"password flag was set and was failed to be parsed",
-					zap.String("step", "arguments initialization"),
-				)
-			}
+		if err := core.ValidateCompression(compress); err != nil {
+			logger.Fatal(
+				err.Error(),
+				zap.String("step", "arguments initialization"),
+			)
+		}
 
-			pwd = res
+		if masterData != 0 && !singleTransaction {
+			logger.Fatal(
+				"--master-data requires --single-transaction",
+				zap.String("step", "arguments initialization"),
+			)
 		}
 
-		cfg := database.NewConfig(user, pwd, hostname, port, args[0])
+		if masterData != 0 && resume {
+			logger.Fatal(
+				"--master-data cannot be used with --resume",
+				zap.String("step", "arguments initialization"),
+			)
+		}
 
-		db, err := sql.Open("mysql", cfg.ConnectionString())
-		if err != nil {
+		// a compressed stream cannot be truncated back to a checkpoint
+		if compress != "" && checkpointPath != "" {
 			logger.Fatal(
-				err.Error(),
-				zap.String("step", "database initialization"),
+				"checkpoints cannot be used with compression",
+				zap.String("step", "arguments initialization"),
 			)
 		}
 
-		service := generator.NewService()
-		var opt []database.Option
+		promptPassword(cmd, logger)
+		db := connect(logger, args)
+		pConf := loadRules(logger)
+		service, opt := newGenerator(cmd, pConf)
 
 		if quick {
 			opt = append(opt, database.OptionValue("quick", ""))
@@ -141,10 +137,56 @@ var rootCmd = &cobra.Command{
 			opt = append(opt, database.OptionValue("dump-trigger", ""))
 		}
 
+		if dumpRoutines {
+			opt = append(opt, database.OptionValue("routines", ""))
+		}
+
+		if dumpEvents {
+			opt = append(opt, database.OptionValue("events", ""))
+		}
+
+		if disableEvents {
+			opt = append(opt, database.OptionValue("disable-events", ""))
+		}
+
 		if skipDefiner {
 			opt = append(opt, database.OptionValue("skip-definer", ""))
 		}
 
+		opt = append(opt, databaseOptions(args)...)
+
+		if skipViews {
+			opt = append(opt, database.OptionValue("skip-views", ""))
+		}
+
+		if parallel > 1 {
+			opt = append(opt, database.OptionValue("parallel", strconv.Itoa(parallel)))
+		}
+
+		if checkpointPath != "" {
+			opt = append(opt, database.OptionValue("checkpoint", checkpointPath))
+		}
+
+		if masterData != 0 {
+			opt = append(opt, database.OptionValue("master-data", strconv.Itoa(masterData)))
+		}
+
+		if subset {
+			opt = append(opt, database.OptionValue("subset", ""))
+		}
+
+		if chunkSize > 0 {
+			opt = append(opt, database.OptionValue("chunk-size", strconv.Itoa(chunkSize)))
+		}
+
+		if compress != "" && outputDir != "" {
+			opt = append(opt, database.OptionValue("compress", compress))
+		}
+
+		if resume {
+			opt = append(opt, database.OptionValue("resume", ""))
+		}
+
 		dumper, err := database.NewMySQLDumper(db, logger, service, opt...)
 		if err != nil {
 			logger.Fatal(
@@ -154,36 +196,68 @@ var rootCmd = &cobra.Command{
 		}
 
 		if configFilePath != "" {
-			d, dErr := os.ReadFile(configFilePath)
-			if dErr != nil {
+			dumper.SetSelectMap(pConf.RewriteToMap())
+			dumper.SetWhereMap(pConf.Where)
+			if dErr := dumper.SetFilterMap(pConf.NoData, pConf.Ignore); dErr != nil {
 				logger.Fatal(
 					dErr.Error(),
-					zap.String("step", "config initialization"),
+					zap.String("step", "config loading"),
 				)
 			}
-
-			pConf, loadErr := core.Load(d)
-			if loadErr != nil {
+			if dErr := dumper.SetRoutineFilter(pConf.IgnoreRoutines); dErr != nil {
 				logger.Fatal(
-					loadErr.Error(),
+					dErr.Error(),
 					zap.String("step", "config loading"),
 				)
 			}
-			dumper.SetSelectMap(pConf.RewriteToMap())
-			dumper.SetWhereMap(pConf.Where)
-			if dErr := dumper.SetFilterMap(pConf.NoData, pConf.Ignore); dErr != nil {
+		}
+
+		if outputDir != "" {
+			if err = os.MkdirAll(outputDir, 0o755); err != nil {
 				logger.Fatal(
-					dErr.Error(),
-					zap.String("step", "config loading"),
+					err.Error(),
+					zap.String("step", "directory initialization"),
+				)
+			}
+
+			create := database.NewDirectoryCreator(outputDir)
+			if compress != "" {
+				plain := create
+				// the manifest is the only file left uncompressed
+				create = func(name string) (io.WriteCloser, error) {
+					f, fErr := plain(name)
+					if fErr != nil || name == database.ManifestFileName {
+						return f, fErr
+					}
+
+					return core.NewCompressor(f, compress, compressLevel)
+				}
+			}
+
+			if err = dumper.DumpToDirectory(create); err != nil {
+				logger.Error(
+					err.Error(),
+					zap.String("step", "dump process"),
 				)
 			}
+
+			return
 		}
 
 		var w io.Writer
 
-		if outputPath == "stdout" {
+		switch {
+		case outputPath == "stdout":
 			w = os.Stdout
-		} else {
+		case resume:
+			// the output is kept, the dump truncates it to the last checkpoint
+			if w, err = os.OpenFile(outputPath, os.O_RDWR, 0); err != nil {
+				logger.Fatal(
+					err.Error(),
+					zap.String("step", "file initialization"),
+				)
+			}
+		default:
 			if w, err = os.Create(outputPath); err != nil {
 				logger.Fatal(
 					err.Error(),
@@ -192,15 +266,208 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
+		if compress != "" {
+			if w, err = core.NewCompressor(w, compress, compressLevel); err != nil {
+				logger.Fatal(
+					err.Error(),
+					zap.String("step", "file initialization"),
+				)
+			}
+		}
+
 		if err = dumper.Dump(w); err != nil {
 			logger.Error(
 				err.Error(),
 				zap.String("step", "dump process"),
 			)
 		}
+
+		// flushes whatever the compressor still holds
+		if c, ok := w.(io.Closer); ok && compress != "" {
+			if err = c.Close(); err != nil {
+				logger.Error(
+					err.Error(),
+					zap.String("step", "file finalization"),
+				)
+			}
+		}
 	},
 }
 
+func newLogger() *zap.Logger {
+	logger, _ := zap.NewProduction()
+
+	if debug {
+		logger, _ = zap.NewDevelopment()
+	}
+
+	return logger
+}
+
+// syncLogger flushes the buffer of logger, if any
+func syncLogger(logger *zap.Logger) {
+	err := logger.Sync()
+	if err != nil &&
+		(!strings.Contains(err.Error(), "invalid argument") && !strings.Contains(
+			err.Error(),
+			"inappropriate ioctl for device",
+		)) {
+		logger.Fatal(
+			err.Error(),
+			zap.String("step", "logger finalization"),
+		)
+	}
+}
+
+func checkDatabaseArgs(logger *zap.Logger, args []string) {
+	switch {
+	case allDatabases && len(args) != 0:
+		logger.Fatal(
+			"no database arguments are allowed with --all-databases",
+			zap.String("step", "arguments initialization"),
+		)
+	case databases && len(args) == 0:
+		logger.Fatal(
+			"at least one database is required with --databases",
+			zap.String("step", "arguments initialization"),
+		)
+	case !allDatabases && !databases && len(args) != 1:
+		logger.Fatal(
+			"database is required",
+			zap.String("step", "arguments initialization"),
+		)
+	}
+}
+
+func promptPassword(cmd *cobra.Command, logger *zap.Logger) {
+	if pwd != "" || !cmd.Flags().Changed("password") {
+		return
+	}
+
+	validate := func(input string) error {
+		if len(input) < 1 {
+			logger.Fatal(
+				"password flag is set, so it is required",
+				zap.String("step", "arguments initialization"),
+			)
+		}
+		return nil
+	}
+
+	prompt := promptui.Prompt{
+		Label:    "Password",
+		Validate: validate,
+		Mask:     '*',
+	}
+
+	res, err := prompt.Run()
+	if err != nil {
+		logger.Fatal(
+			"password flag was set and was failed to be parsed",
+			zap.String("step", "arguments initialization"),
+		)
+	}
+
+	pwd = res
+}
+
+func connect(logger *zap.Logger, args []string) *sql.DB {
+	// when several databases are dumped, the connection is not bound to any of them
+	// and every query is qualified with the schema being dumped instead
+	var dbName string
+	if !allDatabases && !databases {
+		dbName = args[0]
+	}
+
+	cfg := database.NewConfig(user, pwd, hostname, port, dbName)
+
+	db, err := sql.Open("mysql", cfg.ConnectionString())
+	if err != nil {
+		logger.Fatal(
+			err.Error(),
+			zap.String("step", "database initialization"),
+		)
+	}
+
+	return db
+}
+
+func loadRules(logger *zap.Logger) core.Rules {
+	var pConf core.Rules
+	if configFilePath != "" {
+		d, dErr := os.ReadFile(configFilePath)
+		if dErr != nil {
+			logger.Fatal(
+				dErr.Error(),
+				zap.String("step", "config initialization"),
+			)
+		}
+
+		var loadErr error
+		if pConf, loadErr = core.Load(d); loadErr != nil {
+			logger.Fatal(
+				loadErr.Error(),
+				zap.String("step", "config loading"),
+			)
+		}
+	}
+
+	if lErr := generator.ValidateLocale(pConf.Locale); lErr != nil {
+		logger.Fatal(
+			lErr.Error(),
+			zap.String("step", "config loading"),
+		)
+	}
+
+	return pConf
+}
+
+// newGenerator returns the service fake values are generated with, along with the options it requires
+func newGenerator(cmd *cobra.Command, pConf core.Rules) (generator.Service, []database.Option) {
+	locale := generator.WithLocale(pConf.Locale)
+	service := generator.NewService(locale)
+	var opt []database.Option
+
+	// faker.Locale() values change with the locale, so resuming with another one is refused
+	if pConf.Locale != "" {
+		opt = append(opt, database.OptionValue("locale", pConf.Locale))
+	}
+
+	// the flag takes precedence over the config file
+	seeded := cmd.Flags().Changed("seed")
+	if !seeded && pConf.Seed != nil {
+		seed, seeded = *pConf.Seed, true
+	}
+
+	if seeded {
+		opt = append(opt, database.OptionValue("seed", strconv.FormatInt(seed, 10)))
+	}
+
+	switch key := pConf.GetDeterministicKey(); {
+	case key != "":
+		// the same original value always gets the same fake value, in every table and every run
+		service = generator.NewDeterministicService([]byte(key), locale)
+		opt = append(opt, database.OptionValue("deterministic", ""))
+	case seeded:
+		service = generator.NewSeededService(seed, locale)
+	}
+
+	return service, opt
+}
+
+// databaseOptions returns the options of the databases given as args
+func databaseOptions(args []string) []database.Option {
+	if allDatabases {
+		return []database.Option{database.OptionValue("all-databases", "")}
+	}
+
+	if databases {
+		return []database.Option{database.OptionValues("databases", args...)}
+	}
+
+	return nil
+}
+
 var (
 	user              string
 	pwd               string
@@ -222,6 +489,22 @@ var (
 	dumpTrigger       bool
 	skipDefiner       bool
 	triggerDelimiter  string
+	parallel          int
+	databases         bool
+	allDatabases      bool
+	skipViews         bool
+	dumpRoutines      bool
+	dumpEvents        bool
+	disableEvents     bool
+	checkpointPath    string
+	resume            bool
+	outputDir         string
+	compress          string
+	compressLevel     int
+	chunkSize         int
+	masterData        int
+	subset            bool
+	seed              int64
 )
 
 func Execute() error {
@@ -340,7 +623,7 @@ func init() {
 		&hexEncode,
 		"hex-encode",
 		false,
-		"performs hex encoding and respective decode statement for binary values",
+		"hex encodes values of binary columns, when the driver does not report their type",
 	)
 
 	rootCmd.PersistentFlags().BoolVar(
@@ -364,6 +647,27 @@ func init() {
 		"dump triggers",
 	)
 
+	rootCmd.PersistentFlags().BoolVar(
+		&dumpRoutines,
+		"routines",
+		false,
+		"dump stored procedures and functions",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&dumpEvents,
+		"events",
+		false,
+		"dump scheduled events",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&disableEvents,
+		"disable-events",
+		false,
+		"dump events as DISABLE, so they do not run once restored",
+	)
+
 	rootCmd.PersistentFlags().BoolVar(
 		&skipDefiner,
 		"skip-definer",
@@ -377,4 +681,97 @@ func init() {
 		"",
 		"define the char to delimit triggers",
 	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&skipViews,
+		"skip-views",
+		false,
+		"do not dump views",
+	)
+
+	rootCmd.PersistentFlags().IntVar(
+		&parallel,
+		"parallel",
+		1,
+		"number of tables to dump concurrently, each worker uses its own connection",
+	)
+
+	rootCmd.PersistentFlags().BoolVarP(
+		&databases,
+		"databases",
+		"B",
+		false,
+		"dump several databases, every argument is taken as a database name",
+	)
+
+	rootCmd.PersistentFlags().BoolVarP(
+		&allDatabases,
+		"all-databases",
+		"A",
+		false,
+		"dump all databases, except the mysql system schemas",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&checkpointPath,
+		"checkpoint",
+		"",
+		"filepath where the progress of the dump is saved, so it can be resumed",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&resume,
+		"resume",
+		false,
+		"resume an interrupted dump into the same output, checkpoint defaults to the output with .checkpoint",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&outputDir,
+		"output-dir",
+		"",
+		"directory to output one schema and one data file per table, along with a metadata.json manifest",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&compress,
+		"compress",
+		"",
+		"compresses the output with gzip or zstd, detected from the output extension (.gz or .zst) when not set",
+	)
+
+	rootCmd.PersistentFlags().IntVar(
+		&compressLevel,
+		"compress-level",
+		0,
+		"compression level, 1 to 9 for gzip and 1 to 22 for zstd, defaults to the algorithm default",
+	)
+
+	rootCmd.PersistentFlags().IntVar(
+		&chunkSize,
+		"chunk-size",
+		0,
+		"reads tables with a primary key in chunks of this many rows, instead of a single query",
+	)
+
+	rootCmd.PersistentFlags().IntVar(
+		&masterData,
+		"master-data",
+		0,
+		"records the binary log position of the snapshot, 1 as CHANGE MASTER TO statements and 2 commented out",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&subset,
+		"subset",
+		false,
+		"carries where rules across foreign keys, to parent and child tables, for a self-consistent slice",
+	)
+
+	rootCmd.PersistentFlags().Int64Var(
+		&seed,
+		"seed",
+		0,
+		"seeds faker for each column from this value, so runs over the same data write the same dump",
+	)
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootCommandTakesDatabaseArguments(t *testing.T) {
+	run := rootCmd.Run
+	defer func() {
+		rootCmd.Run = run
+		databases = false
+		rootCmd.SetArgs(nil)
+	}()
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"database", []string{"mydb"}, []string{"mydb"}},
+		{"several databases", []string{"--databases", "a", "b"}, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			rootCmd.Run = func(cmd *cobra.Command, args []string) {
+				got = args
+			}
+
+			rootCmd.SetArgs(tt.args)
+			assert.Nil(t, rootCmd.Execute())
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
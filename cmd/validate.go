@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/doutorfinancas/go-mad/database"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [database]",
+	Short: "Checks a configuration against the schema it would dump",
+	Long: `Checks that every table and column of the rewrite and where rules exists, that nodata and ignore
+patterns match a table, that rewrites compile and that where clauses are accepted by EXPLAIN.
+Prints every problem found and exits with status 1 when there is any, nothing is dumped`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger()
+		defer syncLogger(logger)
+
+		checkDatabaseArgs(logger, args)
+
+		if configFilePath == "" {
+			logger.Fatal(
+				"validate requires --config",
+				zap.String("step", "arguments initialization"),
+			)
+		}
+
+		promptPassword(cmd, logger)
+		db := connect(logger, args)
+		pConf := loadRules(logger)
+		service, opt := newGenerator(cmd, pConf)
+		opt = append(opt, databaseOptions(args)...)
+
+		dumper, err := database.NewMySQLDumper(db, logger, service, opt...)
+		if err != nil {
+			logger.Fatal(
+				err.Error(),
+				zap.String("step", "config initialization"),
+			)
+		}
+
+		dumper.SetSelectMap(pConf.RewriteToMap())
+		dumper.SetWhereMap(pConf.Where)
+
+		problems, err := dumper.Validate(pConf.NoData, pConf.Ignore)
+		if err != nil {
+			logger.Fatal(
+				err.Error(),
+				zap.String("step", "validation"),
+			)
+		}
+
+		out := cmd.OutOrStdout()
+		if len(problems) == 0 {
+			_, _ = fmt.Fprintf(out, "%s is valid\n", configFilePath)
+			return
+		}
+
+		for _, problem := range problems {
+			_, _ = fmt.Fprintln(out, problem)
+		}
+
+		_, _ = fmt.Fprintf(out, "\n%d problems found in %s\n", len(problems), configFilePath)
+
+		syncLogger(logger)
+		os.Exit(1)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
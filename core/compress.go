@@ -0,0 +1,99 @@
+package core
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// CompressionExtension is the file extension added by algorithm, none when it is empty.
+func CompressionExtension(algorithm string) string {
+	switch algorithm {
+	case CompressGzip:
+		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	}
+
+	return ""
+}
+
+// CompressionFromPath detects the algorithm from the extension of path, empty when it is not compressed.
+func CompressionFromPath(path string) string {
+	for _, algorithm := range []string{CompressGzip, CompressZstd} {
+		if strings.HasSuffix(path, CompressionExtension(algorithm)) {
+			return algorithm
+		}
+	}
+
+	return ""
+}
+
+// ValidateCompression fails for any algorithm other than the supported ones.
+func ValidateCompression(algorithm string) error {
+	if algorithm != "" && CompressionExtension(algorithm) == "" {
+		return fmt.Errorf("unsupported compression %q, use %s or %s", algorithm, CompressGzip, CompressZstd)
+	}
+
+	return nil
+}
+
+type compressor struct {
+	io.WriteCloser
+	w io.Writer
+}
+
+// Close flushes whatever is left in the compressor and then closes the underlying writer, if it can be closed.
+func (c *compressor) Close() error {
+	err := c.WriteCloser.Close()
+
+	if closer, ok := c.w.(io.Closer); ok {
+		if cErr := closer.Close(); err == nil {
+			err = cErr
+		}
+	}
+
+	return err
+}
+
+// NewCompressor wraps w so everything written is compressed with algorithm as a stream,
+// level 0 stands for the default level of the algorithm.
+func NewCompressor(w io.Writer, algorithm string, level int) (io.WriteCloser, error) {
+	var c io.WriteCloser
+	var err error
+
+	switch algorithm {
+	case CompressGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+
+		c, err = gzip.NewWriterLevel(w, level)
+	case CompressZstd:
+		opts := []zstd.EOption{
+			// a single encoder goroutine keeps memory usage flat, however big the dump is
+			zstd.WithEncoderConcurrency(1),
+		}
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+
+		c, err = zstd.NewWriter(w, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", algorithm)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &compressor{WriteCloser: c, w: w}, nil
+}
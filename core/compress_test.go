@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCompressionFromPath(t *testing.T) {
+	assert.Equal(t, CompressGzip, CompressionFromPath("dump.sql.gz"))
+	assert.Equal(t, CompressZstd, CompressionFromPath("dump.sql.zst"))
+	assert.Equal(t, "", CompressionFromPath("dump.sql"))
+}
+
+func TestValidateCompression(t *testing.T) {
+	assert.Nil(t, ValidateCompression(""))
+	assert.Nil(t, ValidateCompression(CompressGzip))
+	assert.Nil(t, ValidateCompression(CompressZstd))
+	assert.NotNil(t, ValidateCompression("lz4"))
+}
+
+func TestNewCompressor(t *testing.T) {
+	dump := strings.Repeat("INSERT INTO `users` (`id`) VALUES\n( '1' );\n", 1000)
+
+	tests := []struct {
+		algorithm  string
+		level      int
+		decompress func(r io.Reader) (io.Reader, error)
+	}{
+		{
+			algorithm: CompressGzip,
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		{
+			algorithm: CompressGzip,
+			level:     9,
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		{
+			algorithm: CompressZstd,
+			level:     19,
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return zstd.NewReader(r)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			out := new(closeRecorder)
+
+			w, err := NewCompressor(out, tt.algorithm, tt.level)
+			assert.Nil(t, err)
+
+			_, err = io.WriteString(w, dump)
+			assert.Nil(t, err)
+			assert.Nil(t, w.Close())
+			assert.True(t, out.closed)
+			assert.Less(t, out.Len(), len(dump))
+
+			r, err := tt.decompress(out)
+			assert.Nil(t, err)
+
+			b, err := io.ReadAll(r)
+			assert.Nil(t, err)
+			assert.Equal(t, dump, string(b))
+		})
+	}
+
+	_, err := NewCompressor(new(bytes.Buffer), "lz4", 0)
+	assert.NotNil(t, err)
+}
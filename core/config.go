@@ -1,14 +1,24 @@
 package core
 
 import (
+	"os"
+
 	"gopkg.in/yaml.v3"
 )
 
+// DeterministicKeyEnv is the environment variable the deterministic key can be set with,
+// instead of keeping it in the config file. It takes precedence over the config file
+const DeterministicKeyEnv = "GO_MAD_DETERMINISTIC_KEY"
+
 type Rules struct {
-	Rewrite map[string]Rewrite `yaml:"rewrite" json:"rewrite"`
-	NoData  []string           `yaml:"nodata"  json:"nodata"`
-	Ignore  []string           `yaml:"ignore"  json:"ignore"`
-	Where   map[string]string  `yaml:"where"   json:"where"`
+	Rewrite          map[string]Rewrite `yaml:"rewrite"           json:"rewrite"`
+	NoData           []string           `yaml:"nodata"            json:"nodata"`
+	Ignore           []string           `yaml:"ignore"            json:"ignore"`
+	IgnoreRoutines   []string           `yaml:"ignore_routines"   json:"ignore_routines"`
+	Where            map[string]string  `yaml:"where"             json:"where"`
+	DeterministicKey string             `yaml:"deterministic_key" json:"deterministic_key"`
+	Seed             *int64             `yaml:"seed"              json:"seed"`
+	Locale           string             `yaml:"locale"            json:"locale"`
 }
 
 type Rewrite map[string]string
@@ -34,3 +44,13 @@ func (r Rules) RewriteToMap() map[string]map[string]string {
 
 	return selectMap
 }
+
+// GetDeterministicKey returns the key fake values are generated from the original ones with,
+// which is empty when they are random
+func (r Rules) GetDeterministicKey() string {
+	if key := os.Getenv(DeterministicKeyEnv); key != "" {
+		return key
+	}
+
+	return r.DeterministicKey
+}
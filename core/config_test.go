@@ -87,6 +87,40 @@ where:
 			},
 			false,
 		},
+		{
+			"ignore routines",
+			[]byte(`ignore_routines:
+  - tmp_*
+  - billing.sp_rebuild`),
+			Rules{
+				IgnoreRoutines: []string{"tmp_*", "billing.sp_rebuild"},
+			},
+			false,
+		},
+		{
+			"deterministic key",
+			[]byte(`deterministic_key: s3cr3t`),
+			Rules{
+				DeterministicKey: "s3cr3t",
+			},
+			false,
+		},
+		{
+			"seed",
+			[]byte(`seed: 42`),
+			Rules{
+				Seed: func() *int64 { seed := int64(42); return &seed }(),
+			},
+			false,
+		},
+		{
+			"locale",
+			[]byte(`locale: pt`),
+			Rules{
+				Locale: "pt",
+			},
+			false,
+		},
 		{
 			"invalid yaml",
 			[]byte("a: 1\nb: 2\na: 3\n"),
@@ -145,3 +179,12 @@ func TestRules_RewriteToMap(t *testing.T) {
 		)
 	}
 }
+
+func TestRules_GetDeterministicKey(t *testing.T) {
+	r := Rules{DeterministicKey: "from config"}
+	assert.Equal(t, "from config", r.GetDeterministicKey())
+
+	t.Setenv(DeterministicKeyEnv, "from env")
+	assert.Equal(t, "from env", r.GetDeterministicKey())
+	assert.Equal(t, "from env", Rules{}.GetDeterministicKey())
+}
@@ -0,0 +1,483 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckpointInterval is the minimum time between two checkpoints saved
+// while the data of a single table is being written
+const CheckpointInterval = 10 * time.Second
+
+// checkpointState is what gets persisted to disk, it always describes a point
+// of the output where every statement before Offset is complete
+type checkpointState struct {
+	ConfigHash string `json:"config_hash"`
+	SchemaHash string `json:"schema_hash"`
+	// Offset is the size of the output that is known to be complete
+	Offset int64 `json:"offset"`
+	// Done lists every table already written, with its rule key
+	Done []string `json:"done"`
+	// Schemas lists every schema already written, views included, when several databases are dumped
+	Schemas []string `json:"schemas,omitempty"`
+	// Table is the table being written, which started at TableOffset
+	Table       string `json:"table,omitempty"`
+	TableOffset int64  `json:"table_offset,omitempty"`
+	// LastKey holds the primary key of the last row of Table written before Offset
+	LastKey [][]byte `json:"last_key,omitempty"`
+	// Rows counts the rows of Table written before Offset, for templates to go on numbering them
+	Rows int64 `json:"rows,omitempty"`
+}
+
+type checkpoint struct {
+	path  string
+	state checkpointState
+	done  map[string]bool
+	out   *countingWriter
+	saved time.Time
+	// resumeKey is the key the table in progress continues from, once it is used
+	// the table is written like any other
+	resumeKey [][]byte
+}
+
+// countingWriter keeps track of how many bytes the output has
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// outputFile is the subset of *os.File required to resume a dump
+type outputFile interface {
+	io.Writer
+	Truncate(size int64) error
+	Seek(offset int64, whence int) (int64, error)
+}
+
+// startCheckpoint prepares the checkpoint for this run, when resuming it validates
+// that nothing changed and moves w back to the last complete statement. It returns
+// the writer the dump must go through, so the checkpoint knows the output size
+func (d *mySQL) startCheckpoint(w io.Writer, databases []string) (io.Writer, error) {
+	configHash, err := d.configHash(databases)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaHash, err := d.schemaHash(databases)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &checkpoint{
+		path: d.checkpointPath,
+		done: make(map[string]bool),
+		out:  &countingWriter{w: w},
+	}
+
+	if d.resume {
+		if err = cp.load(); err != nil {
+			return nil, err
+		}
+
+		if cp.state.ConfigHash != configHash {
+			return nil, errors.New("configuration changed since the checkpoint was saved, refusing to resume")
+		}
+
+		if cp.state.SchemaHash != schemaHash {
+			return nil, errors.New("schema changed since the checkpoint was saved, refusing to resume")
+		}
+
+		if err = cp.rewind(w, d.parallel > 1); err != nil {
+			return nil, err
+		}
+	}
+
+	cp.state.ConfigHash = configHash
+	cp.state.SchemaHash = schemaHash
+	d.checkpoint = cp
+
+	return cp.out, cp.save()
+}
+
+func (c *checkpoint) load() error {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(b, &c.state); err != nil {
+		return err
+	}
+
+	for _, table := range c.state.Done {
+		c.done[table] = true
+	}
+
+	return nil
+}
+
+// rewind truncates the output to the last complete statement. A table in progress
+// continues after its last key, unless it can't, then it is written again
+func (c *checkpoint) rewind(w io.Writer, restartTable bool) error {
+	f, ok := w.(outputFile)
+	if !ok {
+		return errors.New("resuming a dump requires the output to be a file")
+	}
+
+	if c.state.Table != "" && (c.state.LastKey == nil || restartTable) {
+		c.state.Offset = c.state.TableOffset
+		c.state.Table = ""
+		c.state.LastKey = nil
+		c.state.Rows = 0
+	}
+
+	c.resumeKey = c.state.LastKey
+
+	return c.truncate(f, c.state.Offset)
+}
+
+// restartTable drops whatever was written of the table in progress, for it to be written again from its start
+func (c *checkpoint) restartTable() error {
+	f, ok := c.out.w.(outputFile)
+	if !ok {
+		return errors.New("resuming a dump requires the output to be a file")
+	}
+
+	c.state.Offset = c.state.TableOffset
+	c.state.Table = ""
+	c.state.LastKey = nil
+	c.state.Rows = 0
+	c.resumeKey = nil
+
+	return c.truncate(f, c.state.Offset)
+}
+
+func (c *checkpoint) truncate(f outputFile, offset int64) error {
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	c.out.n = offset
+
+	return nil
+}
+
+// save writes the checkpoint atomically, after making sure that everything
+// it accounts for is already on disk
+func (c *checkpoint) save() error {
+	if s, ok := c.out.w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	c.saved = time.Now()
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+func (c *checkpoint) isDone(key string) bool {
+	return c.done[key]
+}
+
+func (c *checkpoint) isSchemaDone(schema string) bool {
+	for _, done := range c.state.Schemas {
+		if done == schema {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isResuming tells if key is the table that was interrupted in the middle of its data
+func (c *checkpoint) isResuming(key string) bool {
+	return c.resumeKey != nil && c.state.Table == key
+}
+
+func (c *checkpoint) tableStarted(key string) error {
+	c.state.Table = key
+	c.state.TableOffset = c.out.n
+	c.state.Offset = c.out.n
+	c.state.LastKey = nil
+	c.state.Rows = 0
+
+	return c.save()
+}
+
+// tableProgress records that every row up to lastKey was written, rows of them in all, it is
+// only saved once in a while as it is called after every insert statement
+func (c *checkpoint) tableProgress(lastKey [][]byte, rows int64) error {
+	c.state.Offset = c.out.n
+	c.state.LastKey = lastKey
+	c.state.Rows = rows
+
+	if time.Since(c.saved) < CheckpointInterval {
+		return nil
+	}
+
+	return c.save()
+}
+
+func (c *checkpoint) tableDone(key string) error {
+	c.done[key] = true
+	c.resumeKey = nil
+	c.state.Done = append(c.state.Done, key)
+	c.state.Table = ""
+	c.state.TableOffset = 0
+	c.state.LastKey = nil
+	c.state.Rows = 0
+	c.state.Offset = c.out.n
+
+	return c.save()
+}
+
+// schemaDone records that schema is written up to its views, which are not tracked on their own
+func (c *checkpoint) schemaDone(schema string) error {
+	c.state.Schemas = append(c.state.Schemas, schema)
+	c.state.Offset = c.out.n
+
+	return c.save()
+}
+
+// finish removes the checkpoint once the dump is complete, as there is nothing left to resume
+func (c *checkpoint) finish() error {
+	return os.Remove(c.path)
+}
+
+func (d *mySQL) isTableDone(table string) bool {
+	return d.checkpoint != nil && d.checkpoint.isDone(d.filterKey(table))
+}
+
+func (d *mySQL) isSchemaDone(schema string) bool {
+	return d.checkpoint != nil && d.checkpoint.isSchemaDone(schema)
+}
+
+func (d *mySQL) isTableResuming(table string) bool {
+	return d.checkpoint != nil && d.checkpoint.isResuming(d.filterKey(table))
+}
+
+// canResumeTable tells if table continues where it was interrupted. Tables with rewritten UNIQUE keys are
+// written again instead, as the values taken by the rows already written are not known to this run
+func (d *mySQL) canResumeTable(table string) (bool, error) {
+	if !d.isTableResuming(table) {
+		return false, nil
+	}
+
+	tracked, err := d.hasRewrittenUniqueKeys(table)
+	if err != nil || !tracked {
+		return !tracked, err
+	}
+
+	return false, d.checkpoint.restartTable()
+}
+
+func (d *mySQL) checkpointTableStarted(table string) error {
+	if d.checkpoint == nil {
+		return nil
+	}
+
+	return d.checkpoint.tableStarted(d.filterKey(table))
+}
+
+func (d *mySQL) checkpointTableDone(table string) error {
+	if d.checkpoint == nil {
+		return nil
+	}
+
+	return d.checkpoint.tableDone(d.filterKey(table))
+}
+
+func (d *mySQL) checkpointSchemaDone(schema string) error {
+	if d.checkpoint == nil {
+		return nil
+	}
+
+	return d.checkpoint.schemaDone(schema)
+}
+
+// checkpointTableProgress records the key of the last row written, when there is one,
+// along with how many rows were written
+func (d *mySQL) checkpointTableProgress(lastKey [][]byte, rows int64) error {
+	if d.checkpoint == nil || lastKey == nil {
+		return nil
+	}
+
+	return d.checkpoint.tableProgress(lastKey, rows)
+}
+
+// resumeTable continues the data of a table interrupted in the middle, its
+// structure, header and locks are already in the output
+func (d *mySQL) resumeTable(w io.Writer, table string) error {
+	if _, err := d.prepareTable(table); err != nil {
+		return err
+	}
+
+	if d.lockTables {
+		if _, err := d.mysqlFlushTable(table); err != nil {
+			return err
+		}
+	}
+
+	if err := d.dumpTableData(w, table); err != nil {
+		return err
+	}
+
+	if d.lockTables {
+		if _, err := d.mysqlUnlockTables(); err != nil {
+			return err
+		}
+	}
+
+	var tail string
+	if d.addLocks {
+		tail = d.getUnlockTablesStatement()
+	}
+
+	_, err := fmt.Fprintln(w, tail)
+
+	return err
+}
+
+// configHash identifies every setting that changes the content of the dump
+func (d *mySQL) configHash(databases []string) (string, error) {
+	b, err := json.Marshal(
+		struct {
+			Select          map[string]map[string]string
+			Where           map[string]string
+			Filter          map[string]string
+			Databases       []string
+			Charset         string
+			Quick           bool
+			InsertLimit     int
+			HexBins         bool
+			IgnoreGenerated bool
+			AddLocks        bool
+			LockTables      bool
+			Subset          bool
+			Deterministic   bool
+			Seed            *int64
+			Locale          string
+			Views           bool
+			Triggers        bool
+			Routines        bool
+			Events          bool
+			DisableEvents   bool
+			SkipDefiner     bool
+			Delimiter       string
+			Transaction     bool
+			MasterData      int
+		}{
+			d.selectMap,
+			d.whereMap,
+			d.filterMap,
+			databases,
+			d.charset,
+			d.quick,
+			d.extendedInsertLimit,
+			d.shouldHexBins,
+			d.ignoreGenerated,
+			d.addLocks,
+			d.lockTables,
+			d.subset,
+			d.deterministic,
+			d.seed,
+			d.locale,
+			d.dumpViews,
+			d.dumpTrigger,
+			d.dumpRoutine,
+			d.dumpEvent,
+			d.disableEvents,
+			d.skipDefiner,
+			d.triggerDelimiter,
+			d.singleTransaction,
+			d.masterData,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// schemaHash identifies the columns of every table that is going to be dumped
+func (d *mySQL) schemaHash(databases []string) (string, error) {
+	h := sha256.New()
+
+	if len(databases) == 0 {
+		databases = []string{""}
+	}
+
+	defer func() {
+		d.schema = ""
+	}()
+
+	for _, database := range databases {
+		d.schema = database
+		if err := d.writeSchemaColumns(h); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (d *mySQL) writeSchemaColumns(w io.Writer) error {
+	s, err := d.getSchema()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range s.Tables {
+		for _, c := range t.Columns {
+			if _, err = fmt.Fprintf(w, "%s\x00%s\x00%s\x00%s\n", d.schema, t.Name, c.Name, c.ColumnType); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
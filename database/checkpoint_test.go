@@ -0,0 +1,216 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql.checkpoint")
+
+	cp := &checkpoint{path: path, done: make(map[string]bool), out: &countingWriter{w: &discard{}}}
+
+	assert.Nil(t, cp.tableStarted("users"))
+	_, _ = cp.out.Write([]byte("INSERT"))
+	assert.Nil(t, cp.tableDone("users"))
+	assert.Nil(t, cp.tableStarted("orders"))
+
+	loaded := &checkpoint{path: path, done: make(map[string]bool)}
+	assert.Nil(t, loaded.load())
+	assert.True(t, loaded.isDone("users"))
+	assert.False(t, loaded.isDone("orders"))
+	assert.Equal(t, "orders", loaded.state.Table)
+	assert.Equal(t, int64(6), loaded.state.TableOffset)
+
+	assert.Nil(t, loaded.finish())
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCheckpointRewind(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         checkpointState
+		restartTable  bool
+		expectedSize  int64
+		expectedKey   [][]byte
+		expectedTable string
+		expectedRows  int64
+	}{
+		{
+			name:          "continues after the last key",
+			state:         checkpointState{Offset: 8, Table: "users", TableOffset: 4, LastKey: [][]byte{[]byte("10")}, Rows: 3},
+			expectedSize:  8,
+			expectedKey:   [][]byte{[]byte("10")},
+			expectedTable: "users",
+			expectedRows:  3,
+		},
+		{
+			name:         "restarts a table without key",
+			state:        checkpointState{Offset: 8, Table: "users", TableOffset: 4},
+			expectedSize: 4,
+		},
+		{
+			name:         "restarts a table in parallel mode",
+			state:        checkpointState{Offset: 8, Table: "users", TableOffset: 4, LastKey: [][]byte{[]byte("10")}, Rows: 3},
+			restartTable: true,
+			expectedSize: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "dump.sql"))
+			assert.Nil(t, err)
+			defer f.Close()
+
+			_, err = f.WriteString("0123456789ab")
+			assert.Nil(t, err)
+
+			cp := &checkpoint{state: tt.state, out: &countingWriter{w: f}}
+			assert.Nil(t, cp.rewind(f, tt.restartTable))
+			assert.Equal(t, tt.expectedKey, cp.resumeKey)
+			assert.Equal(t, tt.expectedTable, cp.state.Table)
+			assert.Equal(t, tt.expectedSize, cp.out.n)
+			assert.Equal(t, tt.expectedRows, cp.state.Rows)
+
+			_, err = cp.out.Write([]byte("!"))
+			assert.Nil(t, err)
+
+			b, err := os.ReadFile(f.Name())
+			assert.Nil(t, err)
+			assert.Equal(t, "0123456789ab"[:tt.expectedSize]+"!", string(b))
+		})
+	}
+}
+
+func TestCheckpointRewindRequiresFile(t *testing.T) {
+	cp := &checkpoint{out: &countingWriter{w: &discard{}}}
+	assert.NotNil(t, cp.rewind(&discard{}, false))
+}
+
+func Test_mySQL_refusesResumeWhenConfigChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(d *mySQL)
+	}{
+		{"where", func(d *mySQL) { d.whereMap = map[string]string{"users": "id < 10"} }},
+		{"locale", func(d *mySQL) { d.locale = "pt" }},
+		{"views", func(d *mySQL) { d.dumpViews = false }},
+		{"triggers", func(d *mySQL) { d.dumpTrigger = true }},
+		{"definers", func(d *mySQL) { d.skipDefiner = true }},
+		{"master data", func(d *mySQL) { d.masterData = 2 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := getDB(t)
+			path := filepath.Join(t.TempDir(), "dump.sql.checkpoint")
+
+			f, err := os.Create(filepath.Join(t.TempDir(), "dump.sql"))
+			assert.Nil(t, err)
+			defer f.Close()
+
+			dumper := getInternalMySQLInstance(db, nil)
+			dumper.checkpointPath = path
+			expectSchema(mock, map[string][]string{"users": {"id int"}})
+			_, err = dumper.startCheckpoint(f, nil)
+			assert.Nil(t, err)
+
+			dumper = getInternalMySQLInstance(db, nil)
+			dumper.checkpointPath = path
+			dumper.resume = true
+			tt.change(dumper)
+			expectSchema(mock, map[string][]string{"users": {"id int"}})
+			_, err = dumper.startCheckpoint(f, nil)
+			assert.EqualError(t, err, "configuration changed since the checkpoint was saved, refusing to resume")
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_mySQL_resumeSkipsSchemasDone(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+
+	out := &countingWriter{w: new(strings.Builder)}
+	dumper.checkpoint = &checkpoint{
+		path:  filepath.Join(t.TempDir(), "dump.sql.checkpoint"),
+		state: checkpointState{Done: []string{"billing.users"}, Schemas: []string{"billing"}},
+		done:  map[string]bool{"billing.users": true},
+		out:   out,
+	}
+
+	mock.ExpectQuery("SHOW CREATE DATABASE IF NOT EXISTS `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{"Database", "Create Database"}).
+			AddRow("shop", "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop`"),
+	)
+	mock.ExpectQuery("SHOW FULL TABLES FROM `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}),
+	)
+
+	assert.Nil(t, dumper.dumpDatabases(out, []string{"billing", "shop"}))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.NotContains(t, out.w.(*strings.Builder).String(), "billing")
+	assert.Equal(t, []string{"billing", "shop"}, dumper.checkpoint.state.Schemas)
+	assert.Equal(t, out.n, dumper.checkpoint.state.Offset)
+}
+
+func Test_mySQL_canResumeTable(t *testing.T) {
+	tests := []struct {
+		name         string
+		rules        map[string]string
+		expected     bool
+		expectedSize int64
+	}{
+		{"continues the table", map[string]string{"notes": "go:hash"}, true, 8},
+		{"restarts a table with rewritten unique keys", map[string]string{"email": "go:hash"}, false, 4},
+		{"continues a table with shuffled unique keys", map[string]string{"email": "shuffle"}, true, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "dump.sql"))
+			assert.Nil(t, err)
+			defer f.Close()
+
+			_, err = f.WriteString("0123456789ab")
+			assert.Nil(t, err)
+
+			dumper := getInternalMySQLInstance(nil, nil)
+			dumper.selectMap = map[string]map[string]string{"users": tt.rules}
+			s := newTestSchema(map[string][]string{"users": {"id int", "email", "notes"}})
+			users, _ := s.Table("users")
+			users.UniqueKeys["uq_email"] = []string{"email"}
+			dumper.schemas[""] = s
+
+			cp := &checkpoint{
+				state: checkpointState{Offset: 8, Table: "users", TableOffset: 4, LastKey: [][]byte{[]byte("10")}, Rows: 3},
+				out:   &countingWriter{w: f},
+			}
+			assert.Nil(t, cp.rewind(f, false))
+			dumper.checkpoint = cp
+
+			resuming, err := dumper.canResumeTable("users")
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, resuming)
+			assert.Equal(t, tt.expectedSize, cp.out.n)
+			assert.Equal(t, tt.expected, dumper.isTableResuming("users"))
+
+			b, err := os.ReadFile(f.Name())
+			assert.Nil(t, err)
+			assert.Equal(t, "0123456789ab"[:tt.expectedSize], string(b))
+		})
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -0,0 +1,362 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/doutorfinancas/go-mad/generator"
+	"go.uber.org/zap"
+)
+
+// rowWriter turns the rows of a table into insert statements, batching
+// them across every result set the table is read with
+type rowWriter struct {
+	w        io.Writer
+	insert   string
+	table    string
+	columns  []string
+	types    []string
+	limit    int
+	values   []*sql.RawBytes
+	scanArgs []interface{}
+	data     []string
+	// transforms holds the compiled transform of each column, nil when no column has one
+	transforms []*generator.Transform
+	// fakers holds the compiled faker rule of each column, nil when no column has one
+	fakers []generator.FakerFunc
+	// shuffles holds the shuffled values of each column, nil when no column is shuffled
+	shuffles []*shuffledColumn
+	// templates holds the compiled template of each column, nil when no column has one
+	templates []*generator.Template
+	// rows counts the rows written, including the ones written before the dump was resumed
+	rows int64
+	// row holds the values of the row being written, once shuffled, for them to be generated again
+	row []*sql.RawBytes
+	// uniqueKeys are the UNIQUE keys of the table with a rewritten column, nil when there are none
+	uniqueKeys []*uniqueKey
+}
+
+func (d *mySQL) newRowWriter(w io.Writer, table string, columns []string, keyColumns int) *rowWriter {
+	limit := d.extendedInsertLimit
+	if d.quick {
+		limit = 1
+	}
+
+	// key columns, when selected, come after the columns to be dumped
+	values := make([]*sql.RawBytes, len(columns)+keyColumns)
+	scanArgs := make([]interface{}, len(values))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	return &rowWriter{
+		w:        w,
+		insert:   d.generateInsertStatement(columns, table),
+		table:    table,
+		columns:  columns,
+		limit:    limit,
+		values:   values,
+		scanArgs: scanArgs,
+		row:      make([]*sql.RawBytes, len(columns)),
+	}
+}
+
+// flush writes every pending row as a single insert statement
+func (rw *rowWriter) flush() error {
+	if len(rw.data) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(rw.w, "%s\n%s;\n", rw.insert, strings.Join(rw.data, ",\n"))
+	rw.data = rw.data[:0]
+
+	return err
+}
+
+// closeShuffles removes the files the shuffled values were spilled to
+func (rw *rowWriter) closeShuffles() {
+	for _, s := range rw.shuffles {
+		if s != nil {
+			s.close()
+		}
+	}
+}
+
+// getColumnValue renders the value of column i of the row being written, applying its rule when it is
+// applied in Go. Attempt counts how many times the value was asked for again, see makeUnique
+func (d *mySQL) getColumnValue(rw *rowWriter, i int, col *sql.RawBytes, attempt int) (string, error) {
+	if rw.fakers != nil && rw.fakers[i] != nil {
+		val, err := d.getFakeValue(rw.fakers[i], col, attempt)
+		if err != nil {
+			return "", fmt.Errorf("column %s of table %s: %w", rw.columns[i], rw.table, err)
+		}
+
+		return val, nil
+	}
+
+	if rw.transforms != nil && rw.transforms[i] != nil {
+		return d.getTransformedValue(rw.transforms[i], col, rw.types[i], attempt)
+	}
+
+	if rw.templates != nil && rw.templates[i] != nil {
+		val, err := d.getTemplateValue(rw, i, col, attempt)
+		if err != nil {
+			return "", fmt.Errorf("column %s of table %s: %w", rw.columns[i], rw.table, err)
+		}
+
+		return val, nil
+	}
+
+	return d.getProperEscapedValue(col, rw.table, rw.columns[i], rw.types[i]), nil
+}
+
+// writeRows writes every row of rows, closing it once done. It returns how many rows
+// were read along with the key of the last one, when the key columns were selected
+func (d *mySQL) writeRows(rw *rowWriter, rows *sql.Rows) (read int, lastKey [][]byte, err error) {
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("table", rw.table),
+				zap.String("context", "dumping data, closing rows failed"),
+			)
+		}
+	}(rows)
+
+	if rw.types == nil {
+		if rw.types, err = columnTypeNames(rows, len(rw.columns)); err != nil {
+			return read, lastKey, err
+		}
+	}
+
+	key := rw.values[len(rw.columns):]
+	for rows.Next() {
+		if dErr := rows.Scan(rw.scanArgs...); dErr != nil {
+			return read, lastKey, dErr
+		}
+
+		read++
+		rw.rows++
+
+		vals := make([]string, len(rw.columns))
+		for i, col := range rw.values[:len(rw.columns)] {
+			if rw.shuffles != nil && rw.shuffles[i] != nil {
+				if col, err = rw.shuffles[i].valueFor(key); err != nil {
+					return read, lastKey, fmt.Errorf("shuffling column %s of table %s: %w", rw.columns[i], rw.table, err)
+				}
+			}
+
+			rw.row[i] = col
+			if vals[i], err = d.getColumnValue(rw, i, col, 0); err != nil {
+				return read, lastKey, err
+			}
+		}
+
+		if err = d.makeUnique(rw, vals); err != nil {
+			return read, lastKey, err
+		}
+
+		rw.data = append(rw.data, fmt.Sprintf("( %s )", strings.Join(vals, ", ")))
+
+		// the raw bytes are only valid until the next row is read
+		if len(key) > 0 {
+			lastKey = copyKey(key)
+		}
+
+		if len(rw.data) >= rw.limit {
+			if dErr := rw.flush(); dErr != nil {
+				return read, lastKey, dErr
+			}
+
+			if dErr := d.checkpointTableProgress(lastKey, rw.rows); dErr != nil {
+				return read, lastKey, dErr
+			}
+		}
+	}
+
+	return read, lastKey, rows.Err()
+}
+
+// columnTypeNames returns the database type of the first n columns of rows,
+// which is empty for the ones the driver does not know the type of
+func columnTypeNames(rows *sql.Rows, n int) ([]string, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, n)
+	for i := 0; i < n && i < len(types); i++ {
+		names[i] = types[i].DatabaseTypeName()
+	}
+
+	return names, nil
+}
+
+// dumpKeyedTableData reads the table ordered by key, in chunks of chunkSize rows when it is
+// set, each chunk starting right after the last key of the previous one. This way no
+// result set is kept open for longer than a single chunk takes to read
+func (d *mySQL) dumpKeyedTableData(rw *rowWriter, key []string) error {
+	cols, err := d.getColumnsForSelect(rw.table, true)
+	if err != nil {
+		return err
+	}
+
+	var after [][]byte
+	if d.isTableResuming(rw.table) {
+		after = d.checkpoint.resumeKey
+		rw.rows = d.checkpoint.state.Rows
+	}
+
+	for {
+		query, args, err := d.getKeyedSelectQueryFor(rw.table, cols, key, after)
+		if err != nil {
+			return err
+		}
+
+		rows, qErr := d.conn.QueryContext(context.Background(), query, args...)
+		if a := d.evaluateErrors(qErr, rows); a != nil {
+			return a
+		}
+
+		read, lastKey, wErr := d.writeRows(rw, rows)
+		if wErr != nil {
+			return wErr
+		}
+
+		if d.chunkSize == 0 || read < d.chunkSize {
+			return nil
+		}
+
+		after = lastKey
+	}
+}
+
+// getDataKey returns the key the data of table is read by, which is only required when
+// reading in chunks, recording the progress of each table or seeding. Tables without
+// a primary key have none, and are read with a single query
+func (d *mySQL) getDataKey(table string) ([]string, error) {
+	// seeded dumps read the rows in the same order on every run, for them to get the same values,
+	// and shuffled values are handed out in the order the rows were shuffled in
+	if d.chunkSize == 0 && d.checkpoint == nil && d.seed == nil && !d.hasShuffles(table) {
+		return nil, nil
+	}
+
+	return d.getPrimaryKey(table)
+}
+
+func copyKey(key []*sql.RawBytes) [][]byte {
+	lastKey := make([][]byte, 0, len(key))
+	for _, value := range key {
+		if value == nil {
+			lastKey = append(lastKey, nil)
+			continue
+		}
+
+		lastKey = append(lastKey, append([]byte{}, *value...))
+	}
+
+	return lastKey
+}
+
+// getPrimaryKey returns the columns of the primary key of table, in order,
+// or nothing when the table does not have one
+func (d *mySQL) getPrimaryKey(table string) ([]string, error) {
+	t, err := d.getTable(table)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.PrimaryKey, nil
+}
+
+// getKeyedSelectQueryFor returns the same query as getSelectQueryFor, but ordered by key,
+// only with the rows after the given key values when there are any and limited to chunkSize
+// rows when it is set. The raw key columns are selected last, as the rewrite rules may replace them
+func (d *mySQL) getKeyedSelectQueryFor(table string, cols, key []string, after [][]byte) (
+	query string,
+	args []interface{},
+	err error,
+) {
+	keyColumns := make([]string, 0, len(key))
+	placeholders := make([]string, 0, len(key))
+	for _, column := range key {
+		keyColumns = append(keyColumns, d.qualify(table)+"."+quoteIdentifier(column))
+		placeholders = append(placeholders, "?")
+	}
+
+	query = fmt.Sprintf(
+		"SELECT %s, %s FROM %s",
+		strings.Join(cols, ", "),
+		strings.Join(keyColumns, ", "),
+		d.qualify(table),
+	)
+
+	var conditions []string
+	if where, ok := d.whereFor(table); ok {
+		conditions = append(conditions, fmt.Sprintf("(%s)", where))
+	}
+
+	if after != nil {
+		conditions = append(
+			conditions,
+			fmt.Sprintf("(%s) > (%s)", strings.Join(keyColumns, ", "), strings.Join(placeholders, ", ")),
+		)
+
+		if args, err = d.getKeyArgs(table, key, after); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if len(conditions) > 0 {
+		query = fmt.Sprintf("%s WHERE %s", query, strings.Join(conditions, " AND "))
+	}
+
+	query = fmt.Sprintf("%s ORDER BY %s", query, strings.Join(keyColumns, ", "))
+
+	if d.chunkSize > 0 {
+		query = fmt.Sprintf("%s LIMIT %d", query, d.chunkSize)
+	}
+
+	return query, args, nil
+}
+
+// getKeyArgs returns the values of key as the types of its columns. Integers bound as strings
+// would be compared as doubles, which cannot tell apart the BIGINT values above 2^53
+func (d *mySQL) getKeyArgs(table string, key []string, values [][]byte) ([]interface{}, error) {
+	t, err := d.getTable(table)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		c, ok := t.Column(key[i])
+
+		switch {
+		case value == nil || !ok || !c.IsInteger():
+			args = append(args, value)
+		case c.IsUnsigned():
+			n, pErr := strconv.ParseUint(string(value), 10, 64)
+			if pErr != nil {
+				return nil, fmt.Errorf("key %s of table %s: %w", c.Name, table, pErr)
+			}
+
+			args = append(args, n)
+		default:
+			n, pErr := strconv.ParseInt(string(value), 10, 64)
+			if pErr != nil {
+				return nil, fmt.Errorf("key %s of table %s: %w", c.Name, table, pErr)
+			}
+
+			args = append(args, n)
+		}
+	}
+
+	return args, nil
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLGetKeyedSelectQueryFor(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	dumper.whereMap = map[string]string{"users": "active = 1"}
+	dumper.schemas[""] = newTestSchema(map[string][]string{"users": {"id int", "tenant", "email"}})
+	cols := []string{"`id`", "`tenant`", "'faker.Internet.Email' AS `email`"}
+
+	query, args, err := dumper.getKeyedSelectQueryFor("users", cols, []string{"tenant", "id"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		"SELECT `id`, `tenant`, 'faker.Internet.Email' AS `email`, `users`.`tenant`, `users`.`id` FROM `users` "+
+			"WHERE (active = 1) ORDER BY `users`.`tenant`, `users`.`id`",
+		query,
+	)
+	assert.Nil(t, args)
+
+	dumper.chunkSize = 1000
+	query, args, err = dumper.getKeyedSelectQueryFor("users", cols, []string{"tenant", "id"}, [][]byte{[]byte("1"), []byte("10")})
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		"SELECT `id`, `tenant`, 'faker.Internet.Email' AS `email`, `users`.`tenant`, `users`.`id` FROM `users` "+
+			"WHERE (active = 1) AND (`users`.`tenant`, `users`.`id`) > (?, ?) ORDER BY `users`.`tenant`, `users`.`id` LIMIT 1000",
+		query,
+	)
+	assert.Equal(t, []interface{}{[]byte("1"), int64(10)}, args)
+}
+
+func TestMySQLGetKeyArgs(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	s := newTestSchema(map[string][]string{"events": {"id bigint", "seq bigint", "code", "token varbinary"}})
+	events, _ := s.Table("events")
+	seq, _ := events.Column("seq")
+	seq.ColumnType = "bigint unsigned"
+	dumper.schemas[""] = s
+
+	// integers are bound as numbers, as strings they would be compared as doubles and lose their last digits
+	args, err := dumper.getKeyArgs(
+		"events",
+		[]string{"id", "seq", "code", "token"},
+		[][]byte{[]byte("-9007199254740993"), []byte("18446744073709551615"), []byte("Be"), {0xff}},
+	)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		[]interface{}{int64(-9007199254740993), uint64(18446744073709551615), []byte("Be"), []byte{0xff}},
+		args,
+	)
+
+	_, err = dumper.getKeyArgs("events", []string{"id"}, [][]byte{[]byte("1.5")})
+	assert.EqualError(t, err, `key id of table events: strconv.ParseInt: parsing "1.5": invalid syntax`)
+}
+
+func TestMySQLDumpTableDataInChunks(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.chunkSize = 2
+	dumper.whereMap = map[string]string{"users": "id < 10"}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "name"}}, primaryKey("users", "id")...)
+	mock.ExpectQuery("SELECT `id`, `name`, `users`.`id` FROM `users` WHERE \\(id < 10\\) ORDER BY `users`.`id` LIMIT 2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "id"}).AddRow(1, "a", 1).AddRow(2, "b", 2))
+	mock.ExpectQuery("SELECT `id`, `name`, `users`.`id` FROM `users` WHERE \\(id < 10\\) AND \\(`users`.`id`\\) > \\(\\?\\) ORDER BY `users`.`id` LIMIT 2").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "id"}).AddRow(3, "c", 3))
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// rows of both chunks share the same insert statement
+	assert.Equal(t, "INSERT INTO `users` (`id`, `name`) VALUES\n( '1', 'a' ),\n( '2', 'b' ),\n( '3', 'c' );\n", buffer.String())
+}
+
+func TestMySQLDumpTableDataInChunksOfStringKeys(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.chunkSize = 2
+
+	expectSchema(mock, map[string][]string{"countries": {"code", "name"}}, primaryKey("countries", "code")...)
+	mock.ExpectQuery("SELECT `code`, `name`, `countries`.`code` FROM `countries` ORDER BY `countries`.`code` LIMIT 2").
+		WillReturnRows(sqlmock.NewRows([]string{"code", "name", "code"}).AddRow("at", "Austria", "at").AddRow("Be", "Belgium", "Be"))
+	mock.ExpectQuery("SELECT `code`, `name`, `countries`.`code` FROM `countries` WHERE \\(`countries`.`code`\\) > \\(\\?\\) ORDER BY `countries`.`code` LIMIT 2").
+		WithArgs([]byte("Be")).
+		WillReturnRows(sqlmock.NewRows([]string{"code", "name", "code"}).AddRow("pt", "Portugal", "pt"))
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "countries"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		"INSERT INTO `countries` (`code`, `name`) VALUES\n( 'at', 'Austria' ),\n( 'Be', 'Belgium' ),\n( 'pt', 'Portugal' );\n",
+		buffer.String(),
+	)
+}
+
+func TestMySQLDumpTableDataWithoutKeyIsNotChunked(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.chunkSize = 2
+
+	expectSchema(mock, map[string][]string{"logs": {"message"}})
+	mock.ExpectQuery("SELECT `message` FROM `logs`$").WillReturnRows(
+		sqlmock.NewRows([]string{"message"}).AddRow("a").AddRow("b").AddRow("c"),
+	)
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "logs"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "INSERT INTO `logs` (`message`) VALUES\n( 'a' ),\n( 'b' ),\n( 'c' );\n", buffer.String())
+}
+
+func TestMySQLDumpTableDataRendersColumnTypes(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+
+	expectSchema(mock, map[string][]string{"items": {"id int", "name", "flags bit", "token varbinary"}})
+	mock.ExpectQuery("SELECT `id`, `name`, `flags`, `token` FROM `items`$").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("UNSIGNED INT", 0),
+			sqlmock.NewColumn("name").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("flags").OfType("BIT", []byte{}),
+			sqlmock.NewColumn("token").OfType("VARBINARY", []byte{}),
+		).AddRow(7, "it's", []byte{0x03}, []byte{0xca, 0xfe}),
+	)
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "items"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		"INSERT INTO `items` (`id`, `name`, `flags`, `token`) VALUES\n( 7, 'it\\'s', b'11', 0xcafe );\n",
+		buffer.String(),
+	)
+}
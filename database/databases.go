@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// systemDatabases are never exported when --all-databases is used
+var systemDatabases = []string{
+	"information_schema",
+	"mysql",
+	"performance_schema",
+	"sys",
+}
+
+// getDatabases returns the schemas this dump covers, an empty list means
+// only the database selected by the connection is dumped
+func (d *mySQL) getDatabases() ([]string, error) {
+	if !d.allDatabases || d.databases != nil {
+		return d.databases, nil
+	}
+
+	databases := make([]string, 0)
+
+	rows, err := d.conn.QueryContext(context.Background(), "SHOW DATABASES")
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return databases, a
+	}
+
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("internal", "failed to close rows while getting databases"),
+			)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var database string
+
+		if dErr := rows.Scan(&database); dErr != nil {
+			return databases, dErr
+		}
+
+		if !isSystemDatabase(database) {
+			databases = append(databases, database)
+		}
+	}
+
+	d.databases = databases
+
+	return databases, nil
+}
+
+func isSystemDatabase(database string) bool {
+	for _, system := range systemDatabases {
+		if strings.EqualFold(system, database) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dumpDatabases writes each schema as its own block, creating it when
+// missing and switching to it before any of its tables
+func (d *mySQL) dumpDatabases(w io.Writer, databases []string) error {
+	for _, database := range databases {
+		// when resuming, schemas already written are not created nor switched to again
+		if d.isSchemaDone(database) {
+			continue
+		}
+
+		d.schema = database
+
+		ddl, err := d.getCreateDatabaseStatement(database)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(w, ddl); err != nil {
+			return err
+		}
+
+		if err = d.dumpSchemaTables(w); err != nil {
+			return err
+		}
+
+		if err = d.checkpointSchemaDone(database); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *mySQL) getCreateDatabaseStatement(database string) (string, error) {
+	s := fmt.Sprintf("\n--\n-- Current Database: %s\n--\n\n", quoteIdentifier(database))
+	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE DATABASE IF NOT EXISTS %s", quoteIdentifier(database)))
+
+	var name, ddl string
+	if err := row.Scan(&name, &ddl); err != nil {
+		return "", err
+	}
+
+	s += fmt.Sprintf("%s;\n\n", ddl)
+	s += d.getUseDatabaseStatement(database)
+
+	return s, nil
+}
+
+func (d *mySQL) getUseDatabaseStatement(database string) string {
+	return fmt.Sprintf("USE %s;\n", quoteIdentifier(database))
+}
+
+// qualify returns the identifier used to reference an object in queries,
+// qualified with the schema currently being dumped, when there is one
+func (d *mySQL) qualify(name string) string {
+	if d.schema == "" {
+		return quoteIdentifier(name)
+	}
+
+	return quoteIdentifier(d.schema) + "." + quoteIdentifier(name)
+}
+
+// fromSchema returns the FROM clause for SHOW statements, which is empty
+// when the schema selected by the connection is used
+func (d *mySQL) fromSchema() string {
+	if d.schema == "" {
+		return ""
+	}
+
+	return " FROM " + quoteIdentifier(d.schema)
+}
+
+// schemaExpression returns the SQL expression matching the schema
+// currently being dumped, to be used against information_schema
+func (d *mySQL) schemaExpression() string {
+	if d.schema == "" {
+		return "DATABASE()"
+	}
+
+	return fmt.Sprintf("'%s'", escape(d.schema))
+}
+
+// ruleKeys lists the keys that rules for table can be configured with, the
+// schema qualified one takes precedence over the bare table name
+func (d *mySQL) ruleKeys(table string) []string {
+	table = strings.ToLower(table)
+	if d.schema == "" {
+		return []string{table}
+	}
+
+	return []string{strings.ToLower(d.schema) + "." + table, table}
+}
+
+// filterKey is the key under which table is stored in the filter map
+func (d *mySQL) filterKey(table string) string {
+	return d.ruleKeys(table)[0]
+}
+
+// whereFor returns the condition the rows of table are selected with, which is the
+// one carried across foreign keys when subsetting, and its own where rule otherwise
+func (d *mySQL) whereFor(table string) (string, bool) {
+	if d.subsetMap != nil {
+		where, ok := d.subsetMap[strings.ToLower(table)]
+		return where, ok
+	}
+
+	return d.ownWhereFor(table)
+}
+
+func (d *mySQL) ownWhereFor(table string) (string, bool) {
+	for _, key := range d.ruleKeys(table) {
+		if where, ok := d.whereMap[key]; ok {
+			return where, true
+		}
+	}
+
+	return "", false
+}
+
+func (d *mySQL) selectFor(table string) map[string]string {
+	for _, key := range d.ruleKeys(table) {
+		if columns, ok := d.selectMap[key]; ok {
+			return columns
+		}
+	}
+
+	return nil
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
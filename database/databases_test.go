@@ -0,0 +1,115 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLGetDatabasesSkipsSystemSchemas(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.allDatabases = true
+	mock.ExpectQuery("SHOW DATABASES").WillReturnRows(
+		sqlmock.NewRows([]string{"Database"}).
+			AddRow("information_schema").
+			AddRow("billing").
+			AddRow("mysql").
+			AddRow("performance_schema").
+			AddRow("shop").
+			AddRow("sys"),
+	)
+
+	databases, err := dumper.getDatabases()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"billing", "shop"}, databases)
+
+	// the list is resolved only once
+	databases, err = dumper.getDatabases()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"billing", "shop"}, databases)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLRuleLookupPrefersSchemaQualifiedKeys(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	dumper.whereMap = map[string]string{
+		"users":      "id < 10",
+		"shop.users": "id < 20",
+	}
+	dumper.selectMap = map[string]map[string]string{
+		"users": {"email": "NULL"},
+	}
+
+	where, ok := dumper.whereFor("users")
+	assert.True(t, ok)
+	assert.Equal(t, "id < 10", where)
+	assert.Equal(t, "`users`", dumper.qualify("users"))
+
+	dumper.schema = "shop"
+	where, ok = dumper.whereFor("Users")
+	assert.True(t, ok)
+	assert.Equal(t, "id < 20", where)
+	assert.Equal(t, map[string]string{"email": "NULL"}, dumper.selectFor("users"))
+	assert.Equal(t, "`shop`.`users`", dumper.qualify("users"))
+	assert.Equal(t, "shop.users", dumper.filterKey("Users"))
+	assert.Equal(t, "'shop'", dumper.schemaExpression())
+
+	dumper.schema = "billing"
+	where, ok = dumper.whereFor("users")
+	assert.True(t, ok)
+	assert.Equal(t, "id < 10", where)
+}
+
+func Test_mySQL_dumpsMultipleDatabases(t *testing.T) {
+	db, mock := getDB(t)
+
+	for _, database := range []string{"billing", "shop"} {
+		mock.ExpectQuery("SHOW FULL TABLES FROM `" + database + "`").WillReturnRows(
+			sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+				AddRow("users", "BASE TABLE"),
+		)
+	}
+
+	mock.ExpectQuery("SHOW CREATE DATABASE IF NOT EXISTS `billing`").WillReturnRows(
+		sqlmock.NewRows([]string{"Database", "Create Database"}).
+			AddRow("billing", "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `billing`"),
+	)
+	mock.ExpectQuery("SHOW FULL TABLES FROM `billing`").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+			AddRow("users", "BASE TABLE"),
+	)
+	mock.ExpectQuery("SHOW CREATE DATABASE IF NOT EXISTS `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{"Database", "Create Database"}).
+			AddRow("shop", "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop`"),
+	)
+	mock.ExpectQuery("SHOW FULL TABLES FROM `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+			AddRow("users", "BASE TABLE"),
+	)
+	mock.ExpectQuery("SHOW CREATE TABLE `shop`.`users`").WillReturnRows(
+		sqlmock.NewRows([]string{"Table", "Create Table"}).
+			AddRow("users", "CREATE TABLE `users` (`id` int(11) NOT NULL)"),
+	)
+
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.databases = []string{"billing", "shop"}
+	dumper.lockTables = false
+
+	// only the users table of billing should be ignored, shop is dumped without data
+	assert.Nil(t, dumper.SetFilterMap([]string{"shop.users"}, []string{"billing.users"}))
+	assert.Equal(t, map[string]string{"billing.users": IgnoreMapPlacement, "shop.users": NoDataMapPlacement}, dumper.filterMap)
+
+	b := new(strings.Builder)
+	assert.Nil(t, dumper.Dump(b))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "SET NAMES utf8;\nSET FOREIGN_KEY_CHECKS = 0;\n"))
+	assert.Contains(t, out, "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `billing`;\n\nUSE `billing`;\n")
+	assert.Contains(t, out, "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop`;\n\nUSE `shop`;\n")
+	assert.Equal(t, 1, strings.Count(out, "DROP TABLE IF EXISTS `users`;"))
+	assert.True(t, strings.Index(out, "USE `shop`;") < strings.Index(out, "DROP TABLE IF EXISTS `users`;"))
+}
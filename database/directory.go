@@ -0,0 +1,392 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/doutorfinancas/go-mad/core"
+)
+
+// ManifestFileName is the file listing everything a directory dump contains,
+// it is never compressed so it can always be read
+const ManifestFileName = "metadata.json"
+
+// FileCreator creates each file of a directory dump, given its name
+type FileCreator func(name string) (io.WriteCloser, error)
+
+// Manifest describes the files of a directory dump, in the order they should be restored
+type Manifest struct {
+	StartedAt   time.Time          `json:"started_at"`
+	FinishedAt  time.Time          `json:"finished_at"`
+	Compression string             `json:"compression,omitempty"`
+	MasterData  *BinlogPosition    `json:"master_data,omitempty"`
+	Databases   []ManifestDatabase `json:"databases"`
+}
+
+type ManifestDatabase struct {
+	// Name is empty when only the database selected by the connection was dumped
+	Name         string          `json:"name,omitempty"`
+	SchemaFile   string          `json:"schema_file,omitempty"`
+	Tables       []ManifestTable `json:"tables"`
+	Views        []ManifestView  `json:"views,omitempty"`
+	TriggersFile string          `json:"triggers_file,omitempty"`
+	PostFile     string          `json:"post_file,omitempty"`
+}
+
+type ManifestTable struct {
+	Name       string `json:"name"`
+	SchemaFile string `json:"schema_file"`
+	DataFile   string `json:"data_file,omitempty"`
+	Rows       uint64 `json:"rows"`
+}
+
+type ManifestView struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+// NewDirectoryCreator returns a FileCreator that writes plain files into dir
+func NewDirectoryCreator(dir string) FileCreator {
+	return func(name string) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(dir, name))
+	}
+}
+
+// DumpToDirectory creates a MySQL dump split into several files, the same way mydumper does:
+// a schema and a data file for each table, a file for each view, the triggers, routines and
+// events of each database in their own files, and a manifest listing all of them
+func (d *mySQL) DumpToDirectory(create FileCreator) error {
+	manifest := Manifest{StartedAt: time.Now().UTC(), Compression: d.compression}
+
+	databases, err := d.getDatabases()
+	if err != nil {
+		return err
+	}
+
+	if err = d.startSnapshot(); err != nil {
+		return err
+	}
+
+	defer d.commitTransaction()
+
+	manifest.MasterData = d.binlogPosition
+
+	if len(databases) == 0 {
+		entry, dErr := d.dumpSchemaFiles(create)
+		if dErr != nil {
+			return dErr
+		}
+
+		manifest.Databases = append(manifest.Databases, entry)
+	}
+
+	for _, database := range databases {
+		d.schema = database
+
+		entry, dErr := d.dumpSchemaFiles(create)
+		if dErr != nil {
+			return dErr
+		}
+
+		manifest.Databases = append(manifest.Databases, entry)
+	}
+
+	d.commitTransaction()
+
+	for i := range manifest.Databases {
+		d.schema = manifest.Databases[i].Name
+
+		if err = d.dumpObjectFiles(create, &manifest.Databases[i]); err != nil {
+			return err
+		}
+	}
+
+	manifest.FinishedAt = time.Now().UTC()
+
+	return writeFile(create, ManifestFileName, func(w io.Writer) error {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+
+		return e.Encode(manifest)
+	})
+}
+
+// dumpSchemaFiles writes the files of every table and view of the schema currently being dumped
+func (d *mySQL) dumpSchemaFiles(create FileCreator) (ManifestDatabase, error) {
+	entry := ManifestDatabase{Name: d.schema, Tables: make([]ManifestTable, 0)}
+
+	if d.schema != "" {
+		entry.SchemaFile = d.schemaFileName("-schema-create.sql")
+
+		ddl, err := d.getCreateDatabaseStatement(d.schema)
+		if err != nil {
+			return entry, err
+		}
+
+		if err = writeFile(create, entry.SchemaFile, func(w io.Writer) error {
+			_, wErr := io.WriteString(w, ddl)
+			return wErr
+		}); err != nil {
+			return entry, err
+		}
+	}
+
+	tables, views, err := d.getTablesAndViews()
+	if err != nil {
+		return entry, err
+	}
+
+	if err = d.prepareSubset(tables); err != nil {
+		return entry, err
+	}
+
+	if entry.Tables, err = d.dumpTableFiles(create, tables); err != nil {
+		return entry, err
+	}
+
+	if !d.dumpViews {
+		return entry, nil
+	}
+
+	entry.Views, err = d.dumpViewFiles(create, views)
+
+	return entry, err
+}
+
+// dumpTableFiles writes the files of every table that is not ignored,
+// returning their manifest entries in the original table order
+func (d *mySQL) dumpTableFiles(create FileCreator, tables []string) ([]ManifestTable, error) {
+	entries := make([]ManifestTable, 0, len(tables))
+
+	var pending []string
+	for _, table := range tables {
+		if !d.isTableIgnored(table) {
+			pending = append(pending, table)
+		}
+	}
+
+	if len(pending) == 0 {
+		return entries, nil
+	}
+
+	if d.parallel <= 1 {
+		for _, table := range pending {
+			entry, err := d.dumpTableToFiles(create, table)
+			if err != nil {
+				return entries, err
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return entries, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// workers write straight to their own files, so there is nothing to stage
+	results, wait, err := d.startWorkers(ctx, pending, func(worker *mySQL, table string) tableResult {
+		entry, dErr := worker.dumpTableToFiles(create, table)
+		return tableResult{entry: entry, err: dErr}
+	})
+	if err != nil {
+		return entries, err
+	}
+
+	defer wait()
+
+	for _, table := range pending {
+		res := <-results[table]
+		if res.err != nil {
+			cancel()
+			return entries, res.err
+		}
+
+		entries = append(entries, res.entry)
+	}
+
+	return entries, nil
+}
+
+// dumpTableToFiles writes the structure of table to its schema file and,
+// unless the table is set as nodata, its rows to a data file
+func (d *mySQL) dumpTableToFiles(create FileCreator, table string) (ManifestTable, error) {
+	entry := ManifestTable{Name: table, SchemaFile: d.tableFileName(table, "-schema.sql")}
+
+	ddl, err := d.prepareTable(table)
+	if err != nil {
+		return entry, err
+	}
+
+	if err = writeFile(create, entry.SchemaFile, func(w io.Writer) error {
+		_, wErr := io.WriteString(w, d.getFileHeader()+ddl+getFileFooter())
+		return wErr
+	}); err != nil {
+		return entry, err
+	}
+
+	if d.filterMap[d.filterKey(table)] == NoDataMapPlacement {
+		return entry, nil
+	}
+
+	entry.DataFile = d.tableFileName(table, ".sql")
+	err = writeFile(create, entry.DataFile, func(w io.Writer) error {
+		dump, rows, dErr := d.dumpData(w, d.getFileHeader(), table)
+		if dErr != nil {
+			return dErr
+		}
+
+		entry.Rows = rows
+		_, dErr = fmt.Fprintf(w, "%s\n%s", dump, getFileFooter())
+
+		return dErr
+	})
+
+	return entry, err
+}
+
+// dumpViewFiles writes each view that is not ignored to its own file, the manifest
+// lists them ordered by their dependencies so no placeholders are required
+func (d *mySQL) dumpViewFiles(create FileCreator, views []string) ([]ManifestView, error) {
+	pending, definitions, err := d.getViewDefinitions(views)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ManifestView, 0, len(pending))
+	for _, view := range sortViewsByDependency(pending, definitions) {
+		entry := ManifestView{Name: view, File: d.tableFileName(view, "-schema-view.sql")}
+
+		s := d.getFileHeader()
+		s += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", view)
+		s += getFinalViewStatement(view, definitions[view])
+		s += getFileFooter()
+
+		if err = writeFile(create, entry.File, func(w io.Writer) error {
+			_, wErr := io.WriteString(w, s)
+			return wErr
+		}); err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// dumpObjectFiles writes the triggers of the schema currently being dumped to one file,
+// and its routines and events to another, files are only created when they have content
+func (d *mySQL) dumpObjectFiles(create FileCreator, entry *ManifestDatabase) error {
+	if d.dumpTrigger {
+		b := new(strings.Builder)
+		if err := d.dumpTriggers(b); err != nil {
+			return err
+		}
+
+		name, err := d.writeObjectFile(create, "-schema-triggers.sql", b.String())
+		if err != nil {
+			return err
+		}
+
+		entry.TriggersFile = name
+	}
+
+	b := new(strings.Builder)
+	if d.dumpRoutine {
+		if err := d.dumpRoutines(b); err != nil {
+			return err
+		}
+	}
+
+	if d.dumpEvent {
+		if err := d.dumpEvents(b); err != nil {
+			return err
+		}
+	}
+
+	name, err := d.writeObjectFile(create, "-schema-post.sql", b.String())
+	entry.PostFile = name
+
+	return err
+}
+
+func (d *mySQL) writeObjectFile(create FileCreator, suffix, content string) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+
+	name := d.schemaFileName(suffix)
+
+	return name, writeFile(create, name, func(w io.Writer) error {
+		_, err := io.WriteString(w, d.getFileHeader()+content+getFileFooter())
+		return err
+	})
+}
+
+// getFileHeader is written at the top of every file, so each one can be restored on its own
+func (d *mySQL) getFileHeader() string {
+	s := fmt.Sprintf("SET NAMES %s;\n", d.charset)
+	s += "SET FOREIGN_KEY_CHECKS = 0;\n"
+	s += setSQLModeStatement
+
+	if d.schema != "" {
+		s += d.getUseDatabaseStatement(d.schema)
+	}
+
+	return s
+}
+
+// tableFileName names the files of a table, prefixed by its database when there is one
+func (d *mySQL) tableFileName(table, suffix string) string {
+	suffix += core.CompressionExtension(d.compression)
+	if d.schema == "" {
+		return table + suffix
+	}
+
+	return d.schema + "." + table + suffix
+}
+
+// schemaFileName names the files of a whole database, suffix starts with a dash
+// that is dropped when there is no database name to prefix it
+func (d *mySQL) schemaFileName(suffix string) string {
+	suffix += core.CompressionExtension(d.compression)
+	if d.schema == "" {
+		return strings.TrimPrefix(suffix, "-")
+	}
+
+	return d.schema + suffix
+}
+
+// writeFile creates name and writes it through a buffer, closing it when done
+func writeFile(create FileCreator, name string, write func(w io.Writer) error) error {
+	f, err := create(name)
+	if err != nil {
+		return err
+	}
+
+	b := bufio.NewWriter(f)
+	if err = write(b); err == nil {
+		err = b.Flush()
+	}
+
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+
+	return err
+}
+
+// getFileFooter is written at the bottom of every file, restoring what its header changed,
+// as the single file dump does, so the session a file is restored into is left as it was
+func getFileFooter() string {
+	return "SET FOREIGN_KEY_CHECKS = 1;\n" + restoreSQLModeStatement
+}
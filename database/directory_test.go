@@ -0,0 +1,153 @@
+package database
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryFile struct {
+	strings.Builder
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+// memoryCreator keeps every file of a directory dump in memory
+type memoryCreator struct {
+	mu    sync.Mutex
+	files map[string]*memoryFile
+}
+
+func (c *memoryCreator) create(name string) (io.WriteCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f := new(memoryFile)
+	c.files[name] = f
+
+	return f, nil
+}
+
+func (c *memoryCreator) names() []string {
+	names := make([]string, 0, len(c.files))
+	for name := range c.files {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func Test_mySQL_dumpsToDirectory(t *testing.T) {
+	db, mock := getDB(t)
+
+	mock.ExpectQuery("SHOW FULL TABLES FROM `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+			AddRow("logs", "BASE TABLE").
+			AddRow("users", "BASE TABLE"),
+	)
+	mock.ExpectQuery("SHOW CREATE DATABASE IF NOT EXISTS `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{"Database", "Create Database"}).
+			AddRow("shop", "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop`"),
+	)
+	mock.ExpectQuery("SHOW FULL TABLES FROM `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+			AddRow("logs", "BASE TABLE").
+			AddRow("users", "BASE TABLE").
+			AddRow("users_v", "VIEW"),
+	)
+	mock.ExpectQuery("SHOW CREATE TABLE `shop`.`logs`").WillReturnRows(
+		sqlmock.NewRows([]string{"Table", "Create Table"}).
+			AddRow("logs", "CREATE TABLE `logs` (`id` int(11) NOT NULL)"),
+	)
+	mock.ExpectQuery("SHOW CREATE TABLE `shop`.`users`").WillReturnRows(
+		sqlmock.NewRows([]string{"Table", "Create Table"}).
+			AddRow("users", "CREATE TABLE `users` (`id` int(11) NOT NULL)"),
+	)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `shop`.`users`").WillReturnRows(
+		sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(2),
+	)
+	expectSchema(mock, map[string][]string{"logs": {"id int"}, "users": {"id int"}})
+	mock.ExpectQuery("SELECT `id` FROM `shop`.`users`").WillReturnRows(
+		sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2),
+	)
+	mock.ExpectQuery("SHOW CREATE VIEW `shop`.`users_v`").WillReturnRows(
+		sqlmock.NewRows([]string{"View", "Create View", "character_set_client", "collation_connection"}).
+			AddRow("users_v", "CREATE VIEW `users_v` AS select `id` from `users`", "utf8mb4", "utf8mb4_general_ci"),
+	)
+	mock.ExpectQuery("SHOW TRIGGERS FROM `shop`").WillReturnRows(
+		sqlmock.NewRows([]string{
+			"Trigger", "Event", "Table", "Statement", "Timing", "Created", "sql_mode", "Definer",
+			"character_set_client", "collation_connection", "Database Collation",
+		}),
+	)
+
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.databases = []string{"shop"}
+	dumper.lockTables = false
+	dumper.dumpTrigger = true
+	assert.Nil(t, dumper.SetFilterMap([]string{"logs"}, nil))
+
+	c := &memoryCreator{files: make(map[string]*memoryFile)}
+	assert.Nil(t, dumper.DumpToDirectory(c.create))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// there are no triggers, so no triggers file is created
+	assert.ElementsMatch(
+		t,
+		[]string{
+			"shop-schema-create.sql",
+			"shop.logs-schema.sql",
+			"shop.users-schema.sql",
+			"shop.users.sql",
+			"shop.users_v-schema-view.sql",
+			ManifestFileName,
+		},
+		c.names(),
+	)
+
+	assert.True(t, strings.HasPrefix(c.files["shop.users-schema.sql"].String(), "SET NAMES utf8;\nSET FOREIGN_KEY_CHECKS = 0;\n"+setSQLModeStatement+"USE `shop`;\n"))
+	assert.Contains(t, c.files["shop.users-schema.sql"].String(), "CREATE TABLE `users` (`id` int(11) NOT NULL);")
+	assert.NotContains(t, c.files["shop.users-schema.sql"].String(), "INSERT INTO")
+	assert.Contains(t, c.files["shop.users.sql"].String(), "INSERT INTO `users` (`id`) VALUES\n( '1' ),\n( '2' );")
+	assert.Contains(t, c.files["shop.users_v-schema-view.sql"].String(), "CREATE VIEW `users_v` AS select `id` from `users`;")
+
+	// every file restores what its header changed, as it may be restored on its own
+	for _, name := range []string{"shop.users-schema.sql", "shop.users.sql", "shop.users_v-schema-view.sql"} {
+		assert.True(t, strings.HasSuffix(c.files[name].String(), "SET FOREIGN_KEY_CHECKS = 1;\n"+restoreSQLModeStatement), name)
+	}
+
+	var manifest Manifest
+	assert.Nil(t, json.Unmarshal([]byte(c.files[ManifestFileName].String()), &manifest))
+	assert.Equal(
+		t,
+		[]ManifestDatabase{
+			{
+				Name:       "shop",
+				SchemaFile: "shop-schema-create.sql",
+				Tables: []ManifestTable{
+					{Name: "logs", SchemaFile: "shop.logs-schema.sql"},
+					{Name: "users", SchemaFile: "shop.users-schema.sql", DataFile: "shop.users.sql", Rows: 2},
+				},
+				Views: []ManifestView{{Name: "users_v", File: "shop.users_v-schema-view.sql"}},
+			},
+		},
+		manifest.Databases,
+	)
+}
+
+func Test_mySQL_fileNames(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	assert.Equal(t, "users-schema.sql", dumper.tableFileName("users", "-schema.sql"))
+	assert.Equal(t, "schema-post.sql", dumper.schemaFileName("-schema-post.sql"))
+
+	dumper.schema = "shop"
+	assert.Equal(t, "shop.users-schema.sql", dumper.tableFileName("users", "-schema.sql"))
+	assert.Equal(t, "shop-schema-post.sql", dumper.schemaFileName("-schema-post.sql"))
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+
+	"go.uber.org/zap"
+)
+
+// eventStatusRegExp matches the status of an event, which SHOW CREATE EVENT
+// always writes right after the ON COMPLETION clause
+var eventStatusRegExp = regexp.MustCompile(`ON COMPLETION (?:NOT )?PRESERVE (ENABLE|DISABLE ON (?:SLAVE|REPLICA))`)
+
+func (d *mySQL) dumpEvents(w io.Writer) error {
+	events, err := d.getEvents()
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		sqlMode, timeZone, ddl, err := d.getEvent(event)
+		if err != nil {
+			return err
+		}
+
+		s := fmt.Sprintf("\n--\n-- Event `%s`\n--\n\n", event)
+		s += fmt.Sprintf("DROP EVENT IF EXISTS `%s`;\n", event)
+		s += "SET @saved_time_zone = @@time_zone;\n"
+		s += fmt.Sprintf("SET time_zone = '%s';\n", escape(timeZone))
+		if _, err = io.WriteString(w, s); err != nil {
+			return err
+		}
+
+		if err = d.writeDelimited(w, sqlMode, ddl); err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(w, "SET time_zone = @saved_time_zone;\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *mySQL) getEvents() ([]string, error) {
+	events := make([]string, 0)
+
+	rows, err := d.conn.QueryContext(
+		context.Background(),
+		"SELECT EVENT_NAME FROM information_schema.EVENTS WHERE EVENT_SCHEMA = "+d.schemaExpression()+" ORDER BY EVENT_NAME",
+	)
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return events, a
+	}
+
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("internal", "failed to close rows while getting events"),
+			)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var event string
+
+		if dErr := rows.Scan(&event); dErr != nil {
+			return events, dErr
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (d *mySQL) getEvent(event string) (sqlMode, timeZone, ddl string, err error) {
+	var name, unknown string
+
+	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE EVENT %s", d.qualify(event)))
+	if err = row.Scan(&name, &sqlMode, &timeZone, &ddl, &unknown, &unknown, &unknown); err != nil {
+		return "", "", "", err
+	}
+
+	ddl = d.stripDefiner(ddl)
+	if d.disableEvents {
+		ddl = disableEvent(ddl)
+	}
+
+	return sqlMode, timeZone, ddl, nil
+}
+
+// disableEvent rewrites the status of an event to DISABLE, so a restored
+// database does not start running the scheduled jobs of the original one
+func disableEvent(ddl string) string {
+	loc := eventStatusRegExp.FindStringSubmatchIndex(ddl)
+	if loc == nil {
+		return ddl
+	}
+
+	return ddl[:loc[2]] + "DISABLE" + ddl[loc[3]:]
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_disableEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want string
+	}{
+		{
+			"enabled event",
+			"CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY STARTS '2024-01-01 00:00:00' ON COMPLETION NOT PRESERVE ENABLE DO DELETE FROM t",
+			"CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY STARTS '2024-01-01 00:00:00' ON COMPLETION NOT PRESERVE DISABLE DO DELETE FROM t",
+		},
+		{
+			"disabled on replica, with a body mentioning ENABLE",
+			"CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY ON COMPLETION PRESERVE DISABLE ON SLAVE COMMENT 'x' DO UPDATE t SET s = 'ENABLE'",
+			"CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY ON COMPLETION PRESERVE DISABLE COMMENT 'x' DO UPDATE t SET s = 'ENABLE'",
+		},
+		{
+			"already disabled",
+			"CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY ON COMPLETION NOT PRESERVE DISABLE DO DELETE FROM t",
+			"CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY ON COMPLETION NOT PRESERVE DISABLE DO DELETE FROM t",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.want, disableEvent(tt.ddl))
+			},
+		)
+	}
+}
+
+func Test_mySQL_dumpsEvents(t *testing.T) {
+	db, mock := getDB(t)
+
+	mock.ExpectQuery("SHOW FULL TABLES").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}),
+	)
+	mock.ExpectQuery("SELECT EVENT_NAME FROM information_schema.EVENTS WHERE EVENT_SCHEMA = DATABASE\\(\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"EVENT_NAME"}).AddRow("purge_sessions"))
+	mock.ExpectQuery("SHOW CREATE EVENT `purge_sessions`").WillReturnRows(
+		sqlmock.NewRows([]string{"Event", "sql_mode", "time_zone", "Create Event", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow(
+				"purge_sessions",
+				"NO_ENGINE_SUBSTITUTION",
+				"SYSTEM",
+				"CREATE DEFINER=`root`@`%` EVENT `purge_sessions` ON SCHEDULE EVERY 1 DAY STARTS '2024-01-01 03:00:00' "+
+					"ON COMPLETION NOT PRESERVE ENABLE DO BEGIN\n  DELETE FROM sessions;\nEND",
+				"utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci",
+			),
+	)
+
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.dumpEvent = true
+	dumper.disableEvents = true
+	dumper.skipDefiner = true
+	dumper.triggerDelimiter = "$$"
+
+	b := new(strings.Builder)
+	assert.Nil(t, dumper.Dump(b))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	out := b.String()
+	assert.Contains(
+		t,
+		out,
+		"DROP EVENT IF EXISTS `purge_sessions`;\n"+
+			"SET @saved_time_zone = @@time_zone;\n"+
+			"SET time_zone = 'SYSTEM';\n"+
+			"SET @saved_sql_mode = @@sql_mode;\n"+
+			"SET sql_mode = 'NO_ENGINE_SUBSTITUTION';\n"+
+			"DELIMITER $$\n"+
+			"CREATE EVENT `purge_sessions` ON SCHEDULE EVERY 1 DAY STARTS '2024-01-01 03:00:00' "+
+			"ON COMPLETION NOT PRESERVE DISABLE DO BEGIN\n  DELETE FROM sessions;\nEND $$\n"+
+			"DELIMITER ;\n"+
+			"SET sql_mode = @saved_sql_mode;\n"+
+			"SET time_zone = @saved_time_zone;\n",
+	)
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// MasterDataStatement writes the coordinates of the dump as statements that configure a replica
+	MasterDataStatement = 1
+	// MasterDataComment writes the same statements, but commented out
+	MasterDataComment = 2
+)
+
+// BinlogPosition is the point of the binary log of the server the snapshot of the dump was taken at
+type BinlogPosition struct {
+	File         string `json:"file"`
+	Position     uint64 `json:"position"`
+	GTIDExecuted string `json:"gtid_executed,omitempty"`
+}
+
+// getBinlogPosition reads the current binary log coordinates, along with the executed GTID
+// set when GTIDs are enabled. It must run while writes are blocked, for them to match the snapshot
+func (d *mySQL) getBinlogPosition(ctx context.Context, conn connection) (*BinlogPosition, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		// renamed as of MySQL 8.4
+		rows, err = conn.QueryContext(ctx, "SHOW BINARY LOG STATUS")
+	}
+
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return nil, a
+	}
+
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("internal", "failed to close rows while getting binary log position"),
+			)
+		}
+	}(rows)
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		if rErr := rows.Err(); rErr != nil {
+			return nil, rErr
+		}
+
+		return nil, errors.New("binary logging is not enabled, there is no position to record")
+	}
+
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]interface{}, len(values))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	if err = rows.Scan(scanArgs...); err != nil {
+		return nil, err
+	}
+
+	position := &BinlogPosition{}
+	for i, column := range columns {
+		switch column {
+		case "File":
+			position.File = values[i].String
+		case "Position":
+			if position.Position, err = strconv.ParseUint(values[i].String, 10, 64); err != nil {
+				return nil, err
+			}
+		case "Executed_Gtid_Set":
+			// long sets are split in several lines
+			position.GTIDExecuted = strings.ReplaceAll(values[i].String, "\n", "")
+		}
+	}
+
+	return position, nil
+}
+
+// getMasterDataStatement returns the statements that point a replica restored
+// from this dump to the position it was taken at, when it was requested
+func (d *mySQL) getMasterDataStatement() string {
+	if d.binlogPosition == nil {
+		return ""
+	}
+
+	var prefix string
+	if d.masterData == MasterDataComment {
+		prefix = "-- "
+	}
+
+	s := "\n--\n-- Position to start replication or point-in-time recovery from\n--\n\n"
+	s += fmt.Sprintf(
+		"%sCHANGE MASTER TO MASTER_LOG_FILE='%s', MASTER_LOG_POS=%d;\n",
+		prefix,
+		escape(d.binlogPosition.File),
+		d.binlogPosition.Position,
+	)
+
+	if d.binlogPosition.GTIDExecuted != "" {
+		s += fmt.Sprintf("%sSET @@GLOBAL.GTID_PURGED='%s';\n", prefix, escape(d.binlogPosition.GTIDExecuted))
+	}
+
+	return s + "\n"
+}
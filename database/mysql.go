@@ -1,7 +1,7 @@
 package database
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
@@ -18,13 +18,25 @@ import (
 
 type MySQL interface {
 	Dump(w io.Writer) (err error)
+	DumpToDirectory(create FileCreator) error
 	SetSelectMap(map[string]map[string]string)
 	SetWhereMap(map[string]string)
 	SetFilterMap(noData []string, ignore []string) error
+	SetRoutineFilter(ignore []string) error
+	Validate(noData []string, ignore []string) ([]Problem, error)
+}
+
+// connection is satisfied by both *sql.DB and *sql.Conn, which allows
+// each parallel worker to run on its own dedicated connection
+type connection interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 type mySQL struct {
 	db                  *sql.DB
+	conn                connection
 	log                 *zap.Logger
 	selectMap           map[string]map[string]string
 	whereMap            map[string]string
@@ -35,15 +47,37 @@ type mySQL struct {
 	singleTransaction   bool
 	addLocks            bool
 	randomizerService   generator.Service
-	openTx              *sql.Tx
+	snapshot            *snapshot
 	extendedInsertLimit int
-	mapBins             map[string][]string
-	mapExclusionColumns map[string][]string
+	schemas             map[string]*Schema
 	shouldHexBins       bool
 	ignoreGenerated     bool
 	dumpTrigger         bool
 	skipDefiner         bool
 	triggerDelimiter    string
+	parallel            int
+	schema              string
+	databases           []string
+	allDatabases        bool
+	dumpViews           bool
+	dumpRoutine         bool
+	routineFilter       []glob.Glob
+	dumpEvent           bool
+	disableEvents       bool
+	checkpointPath      string
+	resume              bool
+	checkpoint          *checkpoint
+	compression         string
+	chunkSize           int
+	masterData          int
+	binlogPosition      *BinlogPosition
+	subset              bool
+	subsetMap           map[string]string
+	deterministic       bool
+	seed                *int64
+	locale              string
+	generators          map[string]generator.Service
+	shuffleMemory       int
 }
 
 const (
@@ -53,6 +87,13 @@ const (
 	FakerUsageCheck    = "faker"
 )
 
+// the values are written as the server returns them, zero dates and zero values of
+// AUTO_INCREMENT columns included, which a strict sql_mode would refuse or replace
+const (
+	setSQLModeStatement     = "SET @OLD_SQL_MODE = @@SQL_MODE, SQL_MODE = 'NO_AUTO_VALUE_ON_ZERO';\n"
+	restoreSQLModeStatement = "SET SQL_MODE = @OLD_SQL_MODE;\n"
+)
+
 var skipDefinerRegExp = regexp.MustCompile(`(?m)DEFINER=[^ ]* `)
 
 func NewMySQLDumper(db *sql.DB, logger *zap.Logger, randomizerService generator.Service, options ...Option) (
@@ -61,6 +102,7 @@ func NewMySQLDumper(db *sql.DB, logger *zap.Logger, randomizerService generator.
 ) {
 	m := &mySQL{
 		db:                  db,
+		conn:                db,
 		log:                 logger,
 		quick:               false,
 		charset:             "utf8",
@@ -69,13 +111,16 @@ func NewMySQLDumper(db *sql.DB, logger *zap.Logger, randomizerService generator.
 		addLocks:            true,
 		extendedInsertLimit: ExtendedInsertRows,
 		randomizerService:   randomizerService,
-		mapBins:             make(map[string][]string),
-		mapExclusionColumns: make(map[string][]string),
+		schemas:             make(map[string]*Schema),
+		generators:          make(map[string]generator.Service),
 		shouldHexBins:       false,
 		ignoreGenerated:     false,
 		dumpTrigger:         false,
 		skipDefiner:         false,
 		triggerDelimiter:    "",
+		parallel:            1,
+		dumpViews:           true,
+		shuffleMemory:       ShuffleMemory,
 	}
 
 	err := parseMysqlOptions(m, options)
@@ -97,16 +142,40 @@ func (d *mySQL) SetWhereMap(m map[string]string) {
 func (d *mySQL) SetFilterMap(noData, ignore []string) error {
 	d.filterMap = make(map[string]string)
 
-	t, err := d.getTables()
+	databases, err := d.getDatabases()
+	if err != nil {
+		return err
+	}
+
+	if len(databases) == 0 {
+		return d.setSchemaFilterMap(noData, ignore)
+	}
+
+	defer func() {
+		d.schema = ""
+	}()
+
+	for _, database := range databases {
+		d.schema = database
+		if err = d.setSchemaFilterMap(noData, ignore); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *mySQL) setSchemaFilterMap(noData, ignore []string) error {
+	t, v, err := d.getTablesAndViews()
 	if err != nil {
 		return err
 	}
 	for _, table := range d.listTables(t, noData) {
-		d.filterMap[strings.ToLower(table)] = NoDataMapPlacement
+		d.filterMap[d.filterKey(table)] = NoDataMapPlacement
 	}
 
-	for _, table := range d.listTables(t, ignore) {
-		d.filterMap[strings.ToLower(table)] = IgnoreMapPlacement
+	for _, table := range d.listTables(append(t, v...), ignore) {
+		d.filterMap[d.filterKey(table)] = IgnoreMapPlacement
 	}
 
 	return nil
@@ -115,155 +184,286 @@ func (d *mySQL) SetFilterMap(noData, ignore []string) error {
 // Dump creates a MySQL dump and writes it to an io.Writer
 // returns error in the event something gos wrong in the middle of the dump process
 func (d *mySQL) Dump(w io.Writer) error {
-	var dump string
-	var tmp string
-	dump = fmt.Sprintf("SET NAMES %s;\n", d.charset)
+	dump := fmt.Sprintf("SET NAMES %s;\n", d.charset)
 	dump += "SET FOREIGN_KEY_CHECKS = 0;\n"
+	dump += setSQLModeStatement
 
-	tables, err := d.getTables()
+	databases, err := d.getDatabases()
 	if err != nil {
 		return err
 	}
 
-	for _, table := range tables {
-		if d.filterMap[strings.ToLower(table)] == IgnoreMapPlacement {
-			continue
-		}
+	if err = d.startSnapshot(); err != nil {
+		return err
+	}
 
-		skipData := d.filterMap[strings.ToLower(table)] == NoDataMapPlacement
-		tmp, err = d.getCreateTableStatement(table)
-		if err != nil {
+	defer d.commitTransaction()
+
+	dump += d.getMasterDataStatement()
+
+	if d.checkpointPath != "" {
+		if w, err = d.startCheckpoint(w, databases); err != nil {
 			return err
 		}
 
-		tmp = d.excludeGeneratedColumns(table, tmp)
+		// when resuming, the header was already written by the previous run
+		if d.checkpoint.out.n > 0 {
+			dump = ""
+		}
+	}
+
+	// the header is always written, even when no table nor view is, as the trailer restores what it changed
+	if _, err = io.WriteString(w, dump); err != nil {
+		return err
+	}
+
+	if len(databases) == 0 {
+		err = d.dumpSchemaTables(w)
+	} else {
+		err = d.dumpDatabases(w, databases)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	d.commitTransaction()
+
+	_, err = fmt.Fprintf(w, "SET FOREIGN_KEY_CHECKS = 1;\n%s", restoreSQLModeStatement)
+
+	if dErr := d.dumpObjects(w, databases); dErr != nil {
+		return dErr
+	}
+
+	if err == nil && d.checkpoint != nil {
+		return d.checkpoint.finish()
+	}
+
+	return err
+}
+
+// dumpSchemaTables writes every table of the schema currently being dumped,
+// followed by its views
+func (d *mySQL) dumpSchemaTables(w io.Writer) error {
+	tables, views, err := d.getTablesAndViews()
+	if err != nil {
+		return err
+	}
+
+	if err = d.prepareSubset(tables); err != nil {
+		return err
+	}
+
+	if d.parallel > 1 {
+		err = d.dumpTablesInParallel(w, tables)
+	} else {
+		err = d.dumpTables(w, tables)
+	}
 
-		// this will store if a value we might get is supposed to be hexed cause its binary
-		if d.shouldHexBins {
-			d.parseBinaryRelations(table, tmp)
+	if err != nil || !d.dumpViews {
+		return err
+	}
+
+	return d.dumpSchemaViews(w, views)
+}
+
+// dumpObjects writes everything that must only be restored after all the data,
+// switching back to each schema first when several databases are dumped
+func (d *mySQL) dumpObjects(w io.Writer, databases []string) error {
+	if !d.dumpTrigger && !d.dumpRoutine && !d.dumpEvent {
+		return nil
+	}
+
+	if len(databases) == 0 {
+		return d.dumpSchemaObjects(w)
+	}
+
+	for _, database := range databases {
+		d.schema = database
+		if _, err := fmt.Fprintf(w, "\n%s", d.getUseDatabaseStatement(database)); err != nil {
+			return err
 		}
 
-		dump += tmp
-		if !skipData {
-			dump, err = d.dumpData(w, dump, table)
-			if err != nil {
-				return err
-			}
+		if err := d.dumpSchemaObjects(w); err != nil {
+			return err
 		}
+	}
 
-		if _, err = fmt.Fprintln(w, dump); err != nil {
+	return nil
+}
+
+func (d *mySQL) dumpSchemaObjects(w io.Writer) error {
+	if d.dumpTrigger {
+		if err := d.dumpTriggers(w); err != nil {
 			return err
 		}
+	}
 
-		dump = ""
+	if d.dumpRoutine {
+		if err := d.dumpRoutines(w); err != nil {
+			return err
+		}
 	}
 
-	if d.singleTransaction {
-		err = d.openTx.Commit()
+	if d.dumpEvent {
+		return d.dumpEvents(w)
+	}
+
+	return nil
+}
+
+// dumpTables writes every table sequentially, skipping the ignored ones and
+// those a resumed dump already wrote
+func (d *mySQL) dumpTables(w io.Writer, tables []string) error {
+	for _, table := range tables {
+		if d.isTableIgnored(table) || d.isTableDone(table) {
+			continue
+		}
+
+		resuming, err := d.canResumeTable(table)
 		if err != nil {
-			// we actually don't require this commit to be performed
-			// just making sure everything is fine with the transaction
-			// and no dangling pieces are left. Should log though
-			d.log.Error("could not commit transaction")
+			return err
 		}
-	}
 
-	_, err = fmt.Fprintf(w, "SET FOREIGN_KEY_CHECKS = 1;\n")
+		if resuming {
+			err = d.resumeTable(w, table)
+		} else if err = d.checkpointTableStarted(table); err == nil {
+			err = d.dumpTable(w, table)
+		}
 
-	if d.dumpTrigger {
-		if err := d.dumpTriggers(w); err != nil {
+		if err != nil {
+			return err
+		}
+
+		if err = d.checkpointTableDone(table); err != nil {
 			return err
 		}
 	}
 
-	return err
+	return nil
 }
 
-func (d *mySQL) parseBinaryRelations(table, createTable string) {
-	// no cache, if it is requested, replace existing entry
-	d.mapBins[table] = make([]string, 0)
+// prepareTable returns the create statement of table, without its generated columns when they are ignored
+func (d *mySQL) prepareTable(table string) (string, error) {
+	tmp, err := d.getCreateTableStatement(table)
+	if err != nil {
+		return "", err
+	}
 
-	scanner := bufio.NewScanner(strings.NewReader(createTable))
-	for scanner.Scan() {
-		if strings.Contains(strings.ToLower(scanner.Text()), "binary") || strings.Contains(strings.ToLower(scanner.Text()), "blob") {
-			r := regexp.MustCompile("`([^(]*)`")
-			columnName := r.FindAllStringSubmatch(scanner.Text(), -1)
+	if !d.ignoreGenerated {
+		return tmp, nil
+	}
 
-			if len(columnName) > 0 && len(columnName[0]) > 0 {
-				d.mapBins[table] = append(d.mapBins[table], columnName[0][1])
-			}
+	return d.excludeGeneratedColumns(table, tmp)
+}
+
+// dumpTable writes the structure and data of a single table as one block
+func (d *mySQL) dumpTable(w io.Writer, table string) error {
+	dump, err := d.prepareTable(table)
+	if err != nil {
+		return err
+	}
+
+	if d.filterMap[d.filterKey(table)] != NoDataMapPlacement {
+		dump, _, err = d.dumpData(w, dump, table)
+		if err != nil {
+			return err
 		}
 	}
+
+	_, err = fmt.Fprintln(w, dump)
+
+	return err
 }
 
-func (d *mySQL) excludeGeneratedColumns(table, createTable string) string {
-	d.mapExclusionColumns[table] = make([]string, 0)
-	tmp := ""
+func (d *mySQL) isTableIgnored(table string) bool {
+	return d.filterMap[d.filterKey(table)] == IgnoreMapPlacement
+}
 
-	scanner := bufio.NewScanner(strings.NewReader(createTable))
-	for scanner.Scan() {
-		if !strings.Contains(strings.ToLower(scanner.Text()), "generated always") {
-			tmp += scanner.Text() + "\n"
-		} else {
-			r := regexp.MustCompile("`([^(]*)`")
-			columnName := r.FindAllStringSubmatch(scanner.Text(), -1)
+// commitTransaction ends the snapshot, if there is one, every read after
+// it goes back to the database handle
+func (d *mySQL) commitTransaction() {
+	if d.snapshot == nil {
+		return
+	}
 
-			if len(columnName) > 0 && len(columnName[0]) > 0 {
-				d.mapExclusionColumns[table] = append(d.mapExclusionColumns[table], columnName[0][1])
-			}
+	for _, conn := range d.snapshot.conns {
+		if _, err := conn.ExecContext(context.Background(), "COMMIT"); err != nil {
+			// we actually don't require this commit to be performed
+			// just making sure everything is fine with the transaction
+			// and no dangling pieces are left. Should log though
+			d.log.Error("could not commit transaction")
 		}
+
+		closeConn(d.log, conn)
 	}
 
-	if !d.ignoreGenerated {
-		return createTable
+	d.snapshot = nil
+	d.conn = d.db
+}
+
+// excludeGeneratedColumns strips the definitions of the generated columns of table from its create statement
+func (d *mySQL) excludeGeneratedColumns(table, createTable string) (string, error) {
+	t, err := d.getTable(table)
+	if err != nil {
+		return "", err
 	}
 
-	if createTable[len(createTable)-1:] != "\n" {
-		return tmp[:len(tmp)-1]
+	var prefixes []string
+	for _, column := range t.Columns {
+		if column.Generated {
+			prefixes = append(prefixes, "  "+quoteIdentifier(column.Name)+" ")
+		}
 	}
 
-	return tmp
-}
+	lines := strings.Split(createTable, "\n")
+	kept := make([]string, 0, len(lines))
 
-func (d *mySQL) isColumnBinary(table, columnName string) bool {
-	columnName = strings.Trim(columnName, "`")
-	if val, ok := d.mapBins[table]; ok {
-		for _, b := range val {
-			if b == columnName {
-				return true
+	for _, line := range lines {
+		generated := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(line, prefix) {
+				generated = true
+				break
 			}
 		}
+
+		if !generated {
+			kept = append(kept, line)
+		}
 	}
 
-	return false
+	return strings.Join(kept, "\n"), nil
 }
 
-func (d *mySQL) isColumnExcluded(table, columnName string) bool {
-	if val, ok := d.mapExclusionColumns[table]; ok {
-		for _, b := range val {
-			if b == columnName {
-				return true
-			}
-		}
+// isColumnBinary tells whether the values of column are bytes, which are hex encoded when requested
+func (d *mySQL) isColumnBinary(table, columnName string) bool {
+	t, err := d.getTable(table)
+	if err != nil {
+		return false
 	}
 
-	return false
+	c, ok := t.Column(strings.Trim(columnName, "`"))
+
+	return ok && c.IsBinary()
 }
 
-func (d *mySQL) dumpData(w io.Writer, dump, table string) (string, error) {
+// dumpData writes the data of table, returning what is still pending to be
+// written after it along with the number of rows the table has
+func (d *mySQL) dumpData(w io.Writer, dump, table string) (string, uint64, error) {
 	var cnt uint64
 	var tmp string
 	var err error
 	if d.lockTables {
 		_, err = d.mysqlFlushTable(table)
 		if err != nil {
-			return "", err
+			return "", 0, err
 		}
 	}
 
 	tmp, cnt, err = d.getTableHeader(table)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	dump += tmp
 	if cnt > 0 {
@@ -273,13 +473,13 @@ func (d *mySQL) dumpData(w io.Writer, dump, table string) (string, error) {
 
 		// before the data dump, we need to flush everything to file
 		if _, err = fmt.Fprintln(w, dump); err != nil {
-			return "", err
+			return "", 0, err
 		}
 		// and after flush we need to clear the variable
 		dump = ""
 
 		if dErr := d.dumpTableData(w, table); dErr != nil {
-			return "", dErr
+			return "", 0, dErr
 		}
 
 		if d.addLocks {
@@ -288,12 +488,12 @@ func (d *mySQL) dumpData(w io.Writer, dump, table string) (string, error) {
 	}
 
 	if d.lockTables {
-		if _, dErr := d.mysqlUnlockTables(); err != nil {
-			return "", dErr
+		if _, dErr := d.mysqlUnlockTables(); dErr != nil {
+			return "", 0, dErr
 		}
 	}
 
-	return dump, nil
+	return dump, cnt, nil
 }
 
 func (d *mySQL) listTables(tables, globs []string) []string {
@@ -303,7 +503,7 @@ func (d *mySQL) listTables(tables, globs []string) []string {
 		g := glob.MustCompile(query)
 
 		for _, table := range tables {
-			if g.Match(table) {
+			if g.Match(table) || (d.schema != "" && g.Match(d.schema+"."+table)) {
 				globbed = core.AppendIfNotExists(globbed, table)
 			}
 		}
@@ -313,11 +513,19 @@ func (d *mySQL) listTables(tables, globs []string) []string {
 }
 
 func (d *mySQL) getTables() ([]string, error) {
-	tables := make([]string, 0)
+	tables, _, err := d.getTablesAndViews()
+
+	return tables, err
+}
 
-	rows, err := d.db.Query("SHOW FULL TABLES")
+// getTablesAndViews lists base tables and views separately, from a single query
+func (d *mySQL) getTablesAndViews() (tables, views []string, err error) {
+	tables = make([]string, 0)
+	views = make([]string, 0)
+
+	rows, err := d.conn.QueryContext(context.Background(), "SHOW FULL TABLES"+d.fromSchema())
 	if a := d.evaluateErrors(err, rows); a != nil {
-		return tables, a
+		return tables, views, a
 	}
 
 	defer func(rows *sql.Rows) {
@@ -334,15 +542,18 @@ func (d *mySQL) getTables() ([]string, error) {
 		var tableName, tableType string
 
 		if dErr := rows.Scan(&tableName, &tableType); dErr != nil {
-			return tables, dErr
+			return tables, views, dErr
 		}
 
-		if tableType == "BASE TABLE" {
+		switch tableType {
+		case "BASE TABLE":
 			tables = append(tables, tableName)
+		case "VIEW":
+			views = append(views, tableName)
 		}
 	}
 
-	return tables, nil
+	return tables, views, nil
 }
 
 func (d *mySQL) dumpTableData(w io.Writer, table string) error {
@@ -352,82 +563,305 @@ func (d *mySQL) dumpTableData(w io.Writer, table string) error {
 		return err
 	}
 
-	rows, _, err := d.selectAllDataFor(table)
-	if a := d.evaluateErrors(err, rows); a != nil {
-		return a
+	key, err := d.getDataKey(table)
+	if err != nil {
+		return err
 	}
 
-	defer func(rows *sql.Rows) {
-		dErr := rows.Close()
-		if dErr != nil {
-			d.log.Error(
-				dErr.Error(),
-				zap.String("table", table),
-				zap.String("context", "dumping data, closing rows failed"),
-			)
+	rw := d.newRowWriter(w, table, columns, len(key))
+	if rw.transforms, err = d.getTransformsFor(table, columns); err != nil {
+		return err
+	}
+
+	if rw.fakers, err = d.getFakersFor(table, columns); err != nil {
+		return err
+	}
+
+	if rw.templates, err = d.getTemplatesFor(table, columns); err != nil {
+		return err
+	}
+
+	if rw.shuffles, err = d.getShufflesFor(table, columns, key); err != nil {
+		return err
+	}
+
+	if rw.uniqueKeys, err = d.getUniqueKeysFor(rw); err != nil {
+		return err
+	}
+
+	defer rw.closeShuffles()
+
+	if len(key) > 0 {
+		err = d.dumpKeyedTableData(rw, key)
+	} else {
+		rows, _, sErr := d.selectAllDataFor(table)
+		if a := d.evaluateErrors(sErr, rows); a != nil {
+			return a
 		}
-	}(rows)
 
-	numRows := d.extendedInsertLimit
-	if d.quick {
-		numRows = 1
+		_, _, err = d.writeRows(rw, rows)
 	}
 
-	values := make([]*sql.RawBytes, len(columns))
-	scanArgs := make([]interface{}, len(values))
-	for i := range values {
-		scanArgs[i] = &values[i]
+	if err != nil {
+		return err
 	}
 
-	query := d.generateInsertStatement(columns, table)
-	var data []string
-	for rows.Next() {
-		if dErr := rows.Scan(scanArgs...); err != nil {
-			return dErr
+	return rw.flush()
+}
+
+// getProperEscapedValue renders a value for its column type, as reported by the result set.
+// Columns without a known type are rendered as strings, or hex for binary columns
+func (d *mySQL) getProperEscapedValue(col *sql.RawBytes, table, columnName, columnType string) string {
+	val := "NULL"
+
+	if col == nil {
+		return val
+	}
+
+	if valueKindOf(columnType) != textValue {
+		return renderValue(*col, columnType)
+	}
+
+	if d.shouldHexBins && d.isColumnBinary(table, columnName) {
+		encodedVal := hex.EncodeToString(*col)
+
+		if encodedVal != "" {
+			val = "0x" + encodedVal
+		} else {
+			val = "NULL"
+		}
+	} else {
+		val = fmt.Sprintf("'%s'", escape(string(*col)))
+	}
+
+	return val
+}
+
+// getTransformedValue renders the value a transform replaces col with
+func (d *mySQL) getTransformedValue(t *generator.Transform, col *sql.RawBytes, columnType string, attempt int) (string, error) {
+	value := generator.Value{Null: col == nil, Type: columnType, Attempt: attempt}
+	if col != nil {
+		value.Bytes = *col
+	}
+
+	value, err := t.Apply(value)
+	if err != nil {
+		return "", err
+	}
+
+	if value.Null {
+		return "NULL", nil
+	}
+
+	if value.Default {
+		return "DEFAULT", nil
+	}
+
+	return renderValue(value.Bytes, value.Type), nil
+}
+
+// getFakeValue renders the value a faker rule replaces col with. The original value never makes it
+// to the dump, whatever its type, and deterministic fake values are generated from it
+func (d *mySQL) getFakeValue(generate generator.FakerFunc, col *sql.RawBytes, attempt int) (string, error) {
+	var original []byte
+	if d.deterministic {
+		if col == nil {
+			return "NULL", nil
+		}
+
+		original = generator.RetryOriginal(*col, attempt)
+	}
+
+	val, err := generate(original)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("'%s'", escape(val)), nil
+}
+
+// getFakersFor compiles the faker rules of the columns of table once, for them to be called for every row,
+// which are nil for the columns without one
+func (d *mySQL) getFakersFor(table string, columns []string) ([]generator.FakerFunc, error) {
+	var fakers []generator.FakerFunc
+
+	for i, column := range columns {
+		expression, ok := fakerExpression(d.selectFor(table)[strings.ToLower(strings.Trim(column, "`"))])
+		if !ok {
+			continue
+		}
+
+		generate, err := d.generatorFor(table, column).Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("column %s of table %s: %w", column, table, err)
+		}
+
+		if fakers == nil {
+			fakers = make([]generator.FakerFunc, len(columns))
+		}
+
+		fakers[i] = generate
+	}
+
+	return fakers, nil
+}
+
+// getTransformsFor compiles the transforms of the columns of table, which are nil for the columns without one
+func (d *mySQL) getTransformsFor(table string, columns []string) ([]*generator.Transform, error) {
+	var transforms []*generator.Transform
+
+	for i, column := range columns {
+		rule, ok := d.selectFor(table)[strings.ToLower(strings.Trim(column, "`"))]
+		if !ok || !generator.IsTransform(rule) {
+			continue
 		}
-		var vals []string
-		for i, col := range values {
-			vals = append(vals, d.getProperEscapedValue(col, table, columns[i]))
+
+		t, err := d.generatorFor(table, column).Transform(rule)
+		if err != nil {
+			return nil, fmt.Errorf("column %s of table %s: %w", column, table, err)
 		}
 
-		data = append(data, fmt.Sprintf("( %s )", strings.Join(vals, ", ")))
-		if len(data) >= numRows {
-			fmt.Fprintf(w, "%s\n%s;\n", query, strings.Join(data, ",\n"))
-			data = make([]string, 0)
+		if transforms == nil {
+			transforms = make([]*generator.Transform, len(columns))
 		}
+
+		transforms[i] = t
+	}
+
+	return transforms, nil
+}
+
+// getTemplateValue renders the value the template of column i replaces col with, in the row rw is at.
+// NULL values are kept as NULL, as they are by transforms
+func (d *mySQL) getTemplateValue(rw *rowWriter, i int, col *sql.RawBytes, attempt int) (string, error) {
+	if col == nil {
+		return "NULL", nil
 	}
 
-	if len(data) > 0 {
-		fmt.Fprintf(w, "%s\n%s;\n", query, strings.Join(data, ",\n"))
+	val, err := rw.templates[i].Execute(generator.TemplateData{
+		Table:   rw.table,
+		Column:  strings.Trim(rw.columns[i], "`"),
+		Row:     rw.rows,
+		Value:   string(*col),
+		Attempt: attempt,
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	return fmt.Sprintf("'%s'", escape(val)), nil
 }
 
-func (d *mySQL) getProperEscapedValue(col *sql.RawBytes, table, columnName string) string {
-	val := "NULL"
+// getTemplatesFor compiles the templates of the columns of table, which are nil for the columns without one
+func (d *mySQL) getTemplatesFor(table string, columns []string) ([]*generator.Template, error) {
+	var templates []*generator.Template
 
-	if col != nil {
-		if d.shouldHexBins && d.isColumnBinary(table, columnName) {
-			encodedVal := hex.EncodeToString(*col)
+	for i, column := range columns {
+		rule, ok := d.selectFor(table)[strings.ToLower(strings.Trim(column, "`"))]
+		if !ok || !generator.IsTemplate(rule) {
+			continue
+		}
 
-			if encodedVal != "" {
-				val = "0x" + encodedVal
-			} else {
-				val = "NULL"
-			}
-		} else {
-			val = string(*col)
+		t, err := d.generatorFor(table, column).Template(rule)
+		if err != nil {
+			return nil, fmt.Errorf("column %s of table %s: %w", column, table, err)
+		}
 
-			if len(val) >= 5 && val[0:5] == FakerUsageCheck {
-				val, _ = d.randomizerService.ReplaceStringWithFakerWhenRequested(val)
-			}
+		if templates == nil {
+			templates = make([]*generator.Template, len(columns))
+		}
 
-			val = fmt.Sprintf("'%s'", escape(val))
+		templates[i] = t
+	}
+
+	return templates, nil
+}
+
+// renderValue writes value as a literal of a column of type columnType
+func renderValue(value []byte, columnType string) string {
+	switch valueKindOf(columnType) {
+	case numericValue:
+		return string(value)
+	case bitValue:
+		return bitLiteral(value)
+	case binaryValue:
+		if len(value) == 0 {
+			return "''"
 		}
+
+		return "0x" + hex.EncodeToString(value)
+	default:
+		return fmt.Sprintf("'%s'", escape(string(value)))
 	}
+}
 
-	return val
+const (
+	textValue = iota
+	numericValue
+	bitValue
+	binaryValue
+)
+
+// valueKindOf tells how the values of a column of type columnType are written
+func valueKindOf(columnType string) int {
+	switch strings.TrimPrefix(columnType, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE", "YEAR":
+		return numericValue
+	case "BIT":
+		return bitValue
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "GEOMETRY", "VECTOR":
+		return binaryValue
+	default:
+		return textValue
+	}
+}
+
+// bitLiteral writes the value of a BIT column as a b'...' literal, without leading zeros
+func bitLiteral(value []byte) string {
+	var b strings.Builder
+	for _, c := range value {
+		b.WriteString(fmt.Sprintf("%08b", c))
+	}
+
+	bits := strings.TrimLeft(b.String(), "0")
+	if bits == "" {
+		bits = "0"
+	}
+
+	return "b'" + bits + "'"
+}
+
+// generatorFor returns the service the fake values of column are generated with, created once per
+// column, so a seeded dump generates the values of each column independently of any other column
+func (d *mySQL) generatorFor(table, column string) generator.Service {
+	if d.seed == nil {
+		return d.randomizerService
+	}
+
+	table = d.filterKey(table)
+	column = strings.ToLower(strings.Trim(column, "`"))
+
+	key := table + "." + column
+	service, ok := d.generators[key]
+	if !ok {
+		service = d.randomizerService.ForColumn(table, column)
+		d.generators[key] = service
+	}
+
+	return service
+}
+
+// fakerExpression returns the faker expression of a rewrite, which may be quoted as an SQL string
+func fakerExpression(replacement string) (string, bool) {
+	if len(replacement) >= 2 && replacement[0] == '\'' && replacement[len(replacement)-1] == '\'' {
+		replacement = replacement[1 : len(replacement)-1]
+	}
+
+	if len(replacement) >= 5 && replacement[0:5] == FakerUsageCheck {
+		return replacement, true
+	}
+
+	return "", false
 }
 
 func (d *mySQL) generateInsertStatement(cols []string, table string) string {
@@ -468,7 +902,7 @@ func (d *mySQL) selectAllDataFor(table string) (rows *sql.Rows, columns []string
 	if columns, selectQuery, err = d.getSelectQueryFor(table); err != nil {
 		return
 	}
-	if rows, err = d.db.Query(selectQuery); err != nil {
+	if rows, err = d.conn.QueryContext(context.Background(), selectQuery); err != nil {
 		return
 	}
 
@@ -480,8 +914,8 @@ func (d *mySQL) getSelectQueryFor(table string) (cols []string, query string, er
 	if err != nil {
 		return cols, "", err
 	}
-	query = fmt.Sprintf("SELECT %s FROM `%s`", strings.Join(cols, ", "), table)
-	if where, ok := d.whereMap[strings.ToLower(table)]; ok {
+	query = fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ", "), d.qualify(table))
+	if where, ok := d.whereFor(table); ok {
 		query = fmt.Sprintf("%s WHERE %s", query, where)
 	}
 	return
@@ -496,31 +930,33 @@ func (d *mySQL) getUnlockTablesStatement() string {
 }
 
 func (d *mySQL) getColumnsForSelect(table string, considerRewriteMap bool) (columns []string, err error) {
-	rows, err := d.db.Query(fmt.Sprintf("SELECT * FROM `%s` LIMIT 1", table))
-	if a := d.evaluateErrors(err, rows); a != nil {
-		return columns, a
+	t, err := d.getTable(table)
+	if err != nil {
+		return columns, err
 	}
 
-	defer func(rows *sql.Rows) {
-		dErr := rows.Close()
-		if dErr != nil {
-			d.log.Warn(dErr.Error(), zap.String("table", table))
+	// generated columns cannot be inserted, invisible ones are listed so they are not left out
+	for _, c := range t.Columns {
+		if c.Generated {
+			continue
 		}
-	}(rows)
-	var tmp []string
-	if tmp, err = rows.Columns(); err != nil {
-		return
-	}
 
-	for _, column := range tmp {
-		if d.isColumnExcluded(table, column) {
-			continue
+		column := c.Name
+		replacement, ok := d.selectFor(table)[strings.ToLower(column)]
+		if _, faker := fakerExpression(replacement); ok && faker && d.deterministic {
+			// the fake value is generated from the original one
+			ok = false
+		}
+
+		if ok && (generator.IsTransform(replacement) || generator.IsTemplate(replacement) || isShuffle(replacement)) {
+			// transforms and templates are applied to the original value, which shuffles permute
+			ok = false
 		}
 
-		replacement, ok := d.selectMap[strings.ToLower(table)][strings.ToLower(column)]
 		if ok && considerRewriteMap {
 			if len(replacement) >= 5 && replacement[0:5] == FakerUsageCheck {
-				replacement = fmt.Sprintf("'%s'", replacement)
+				// faker expressions may have quoted arguments of their own
+				replacement = fmt.Sprintf("'%s'", escape(replacement))
 			}
 
 			columns = append(columns, fmt.Sprintf("%s AS `%s`", replacement, column))
@@ -533,8 +969,8 @@ func (d *mySQL) getColumnsForSelect(table string, considerRewriteMap bool) (colu
 }
 
 func (d *mySQL) rowCount(table string) (count uint64, err error) {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table)
-	if where, ok := d.whereMap[strings.ToLower(table)]; ok {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", d.qualify(table))
+	if where, ok := d.whereFor(table); ok {
 		query = fmt.Sprintf("%s WHERE %s", query, where)
 	}
 	row := d.useTransactionOrDBQueryRow(query)
@@ -547,7 +983,7 @@ func (d *mySQL) rowCount(table string) (count uint64, err error) {
 func (d *mySQL) getCreateTableStatement(table string) (string, error) {
 	s := fmt.Sprintf("\n--\n-- Structure for table `%s`\n--\n\n", table)
 	s += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", table)
-	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table))
+	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE TABLE %s", d.qualify(table)))
 	var tname, ddl string
 	if err := row.Scan(&tname, &ddl); err != nil {
 		return "", err
@@ -557,7 +993,7 @@ func (d *mySQL) getCreateTableStatement(table string) (string, error) {
 }
 
 func (d *mySQL) mysqlFlushTable(table string) (sql.Result, error) {
-	return d.useTransactionOrDBExec(fmt.Sprintf("FLUSH TABLES `%s` WITH READ LOCK", table))
+	return d.useTransactionOrDBExec(fmt.Sprintf("FLUSH TABLES %s WITH READ LOCK", d.qualify(table)))
 }
 
 // Release the global read locks
@@ -565,32 +1001,14 @@ func (d *mySQL) mysqlUnlockTables() (sql.Result, error) {
 	return d.useTransactionOrDBExec("UNLOCK TABLES")
 }
 
+// useTransactionOrDBQueryRow runs query on the connection of this dumper, which
+// is bound to its snapshot transaction when the dump runs in a single transaction
 func (d *mySQL) useTransactionOrDBQueryRow(query string) *sql.Row {
-	if d.singleTransaction {
-		return d.getTransaction().QueryRow(query)
-	}
-
-	return d.db.QueryRow(query)
+	return d.conn.QueryRowContext(context.Background(), query)
 }
 
 func (d *mySQL) useTransactionOrDBExec(query string) (sql.Result, error) {
-	if d.singleTransaction {
-		return d.getTransaction().Exec(query)
-	}
-
-	return d.db.Exec(query)
-}
-
-func (d *mySQL) getTransaction() *sql.Tx {
-	if d.openTx == nil {
-		var err error
-		d.openTx, err = d.db.Begin()
-		if err != nil {
-			panic("could not start a transaction")
-		}
-	}
-
-	return d.openTx
+	return d.conn.ExecContext(context.Background(), query)
 }
 
 func (d *mySQL) dumpTriggers(w io.Writer) error {
@@ -627,7 +1045,7 @@ func (d *mySQL) dumpTriggers(w io.Writer) error {
 func (d *mySQL) getTriggers() ([]string, error) {
 	triggers := make([]string, 0)
 
-	rows, err := d.db.Query("SHOW TRIGGERS")
+	rows, err := d.conn.QueryContext(context.Background(), "SHOW TRIGGERS"+d.fromSchema())
 	if a := d.evaluateErrors(err, rows); a != nil {
 		return triggers, a
 	}
@@ -655,17 +1073,22 @@ func (d *mySQL) getTriggers() ([]string, error) {
 	return triggers, nil
 }
 
+// stripDefiner removes the DEFINER clause from a create statement, when requested
+func (d *mySQL) stripDefiner(ddl string) string {
+	if !d.skipDefiner {
+		return ddl
+	}
+
+	return skipDefinerRegExp.ReplaceAllString(ddl, "")
+}
+
 func (d *mySQL) getTrigger(triggerName string) (string, error) {
 	var ddl, unknown string
 
-	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE TRIGGER `%s`", triggerName))
+	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE TRIGGER %s", d.qualify(triggerName)))
 	if err := row.Scan(&unknown, &unknown, &ddl, &unknown, &unknown, &unknown, &unknown); err != nil {
 		return "", err
 	}
 
-	if d.skipDefiner {
-		ddl = skipDefinerRegExp.ReplaceAllString(ddl, "")
-	}
-
-	return ddl + ";\n", nil
+	return d.stripDefiner(ddl) + ";\n", nil
 }
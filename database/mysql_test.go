@@ -115,21 +115,20 @@ func TestMySQLGetColumnsForSelect(t *testing.T) {
 	db, mock := getDB(t)
 	dumper := getInternalMySQLInstance(db, nil)
 	dumper.selectMap = map[string]map[string]string{"table": {"col2": "NOW()"}}
-	mock.ExpectQuery("SELECT \\* FROM `table` LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"col1", "col2", "col3"}).AddRow("a", "b", "c"),
-	)
+	expectSchema(mock, map[string][]string{"table": {"col1 int VIRTUAL GENERATED", "col2", "col3 varchar INVISIBLE"}})
 	columns, err := dumper.getColumnsForSelect("table", true)
 	assert.Nil(t, err)
-	assert.Equal(t, []string{"`col1`", "NOW() AS `col2`", "`col3`"}, columns)
+	assert.Equal(t, []string{"NOW() AS `col2`", "`col3`"}, columns)
+}
 
-	dumper.mapExclusionColumns = map[string][]string{"table": {"col1"}}
-	dumper.ignoreGenerated = true
-	mock.ExpectQuery("SELECT \\* FROM `table` LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"col1", "col2", "col3"}).AddRow("a", "b", "c"),
-	)
-	columns, err = dumper.getColumnsForSelect("table", true)
+func TestMySQLGetColumnsForSelectWithQuotedFakerArguments(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.selectMap = map[string]map[string]string{"table": {"col1": `faker.Numerify('### "#"')`}}
+	expectSchema(mock, map[string][]string{"table": {"col1"}})
+	columns, err := dumper.getColumnsForSelect("table", true)
 	assert.Nil(t, err)
-	assert.Equal(t, []string{"NOW() AS `col2`", "`col3`"}, columns)
+	assert.Equal(t, []string{`'faker.Numerify(\'### \"#\"\')' AS ` + "`col1`"}, columns)
 }
 
 func TestMySQLGetColumnsForSelectHandlingErrorWhenQuerying(t *testing.T) {
@@ -137,10 +136,14 @@ func TestMySQLGetColumnsForSelectHandlingErrorWhenQuerying(t *testing.T) {
 	dumper := getInternalMySQLInstance(db, nil)
 	dumper.selectMap = map[string]map[string]string{"table": {"col2": "NOW()"}}
 	err := errors.New("broken")
-	mock.ExpectQuery("SELECT \\* FROM `table` LIMIT 1").WillReturnError(err)
+	mock.ExpectQuery("FROM information_schema.COLUMNS").WillReturnError(err)
 	columns, dErr := dumper.getColumnsForSelect("table", true)
 	assert.Equal(t, dErr, err)
 	assert.Empty(t, columns)
+
+	expectSchema(mock, map[string][]string{"other": {"c1"}})
+	_, dErr = dumper.getColumnsForSelect("table", true)
+	assert.EqualError(t, dErr, "table table not found in information_schema")
 }
 
 func TestMySQLGetSelectQueryFor(t *testing.T) {
@@ -148,9 +151,7 @@ func TestMySQLGetSelectQueryFor(t *testing.T) {
 	dumper := getInternalMySQLInstance(db, nil)
 	dumper.selectMap = map[string]map[string]string{"table": {"c2": "NOW()"}}
 	dumper.whereMap = map[string]string{"table": "c1 > 0"}
-	mock.ExpectQuery("SELECT \\* FROM `table` LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"c1", "c2"}).AddRow("a", "b"),
-	)
+	expectSchema(mock, map[string][]string{"table": {"c1", "c2"}})
 	_, query, err := dumper.getSelectQueryFor("table")
 	assert.Nil(t, err)
 	assert.Equal(t, "SELECT `c1`, NOW() AS `c2` FROM `table` WHERE c1 > 0", query)
@@ -162,7 +163,7 @@ func TestMySQLGetSelectQueryForHandlingError(t *testing.T) {
 	dumper.selectMap = map[string]map[string]string{"table": {"c2": "NOW()"}}
 	dumper.whereMap = map[string]string{"table": "c1 > 0"}
 	dErr := errors.New("broken")
-	mock.ExpectQuery("SELECT \\* FROM `table` LIMIT 1").WillReturnError(dErr)
+	mock.ExpectQuery("FROM information_schema.COLUMNS").WillReturnError(dErr)
 	_, query, err := dumper.getSelectQueryFor("table")
 	assert.Equal(t, dErr, err)
 	assert.Equal(t, "", query)
@@ -250,15 +251,7 @@ func TestMySQLDumpTableData(t *testing.T) {
 		{6, "Leek"},
 	}
 
-	mock.ExpectQuery("SELECT \\* FROM `vegetable_list` LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"id", "vegetable"}).
-			AddRow(1, "Lettuce"),
-	)
-
-	mock.ExpectQuery("SELECT \\* FROM `vegetable_list` LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"id", "vegetable"}).
-			AddRow(1, "Lettuce"),
-	)
+	expectSchema(mock, map[string][]string{"vegetable_list": {"id int", "vegetable"}})
 
 	rows := sqlmock.NewRows([]string{"id", "vegetable_list"})
 	for _, row := range r {
@@ -276,106 +269,242 @@ func TestMySQLDumpTableData(t *testing.T) {
 	}
 }
 
-func TestMySQLDumpTableDataHandlingErrorFromSelectAllDataFor(t *testing.T) {
+func TestMySQLDumpTableDataDeterministic(t *testing.T) {
 	db, mock := getDB(t)
 	buffer := bytes.NewBuffer(make([]byte, 0))
-	dumper := getInternalMySQLInstance(db, nil)
-	err := errors.New("fail")
-	mock.ExpectQuery("SELECT \\* FROM `table` LIMIT 1").WillReturnError(err)
-	assert.Equal(t, err, dumper.dumpTableData(buffer, "table"))
+
+	ctrl := gomock.NewController(t)
+	gen := mockgenerator.NewMockService(ctrl)
+
+	dumper := getInternalMySQLInstance(db, gen)
+	dumper.deterministic = true
+	dumper.selectMap = map[string]map[string]string{
+		"users": {"email": "'faker.Internet().Email()'", "name": "'faker.Person().Name()'"},
+	}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "email", "name"}})
+
+	// the original values are selected, for the fake ones to be generated from them
+	mock.ExpectQuery("SELECT `id`, `email`, `name` FROM `users`").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "email", "name"}).
+			AddRow(1, "jane@example.com", "Jane").
+			AddRow(2, "jane@example.com", nil),
+	)
+
+	// rules are compiled once per column, and called with the original value of every row
+	var originals []string
+	gen.EXPECT().Compile("faker.Internet().Email()").Return(generator.FakerFunc(func(original []byte) (string, error) {
+		originals = append(originals, string(original))
+		return "fake@example.com", nil
+	}), nil)
+	gen.EXPECT().Compile("faker.Person().Name()").Return(generator.FakerFunc(func(original []byte) (string, error) {
+		originals = append(originals, string(original))
+		return "Mary", nil
+	}), nil)
+
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		"INSERT INTO `users` (`id`, `email`, `name`) VALUES\n"+
+			"( '1', 'fake@example.com', 'Mary' ),\n( '2', 'fake@example.com', NULL );\n",
+		buffer.String(),
+	)
+	assert.Equal(t, []string{"jane@example.com", "Jane", "jane@example.com"}, originals)
 }
 
-func Test_mySQL_parseBinaryRelations(t *testing.T) {
-	db, _ := getDB(t)
-	type args struct {
-		table       string
-		createTable string
-		expectedMap map[string][]string
+func TestMySQLDumpTableDataSeeded(t *testing.T) {
+	db, mock := getDB(t)
+	buffer := bytes.NewBuffer(make([]byte, 0))
+
+	ctrl := gomock.NewController(t)
+	gen := mockgenerator.NewMockService(ctrl)
+	names := mockgenerator.NewMockService(ctrl)
+
+	seed := int64(42)
+	dumper := getInternalMySQLInstance(db, gen)
+	dumper.seed = &seed
+	dumper.selectMap = map[string]map[string]string{
+		"users": {"name": "'faker.Person().Name()'"},
 	}
-	tests := []struct {
-		name string
-		args args
-	}{
-		{
-			"manage create table successfully with binary",
-			args{
-				"table",
-				`CREATE TABLE ` + "`table`" + ` (
-  ` + "`id`" + ` binary(16) NOT NULL AUTO_INCREMENT,
-  ` + "`s`" + ` char(60) DEFAULT NULL,
-  PRIMARY KEY (` + "`id`" + `)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
-				map[string][]string{
-					"table": {"id"},
-				},
-			},
-		},
-		{
-			"manage create table successfully with blob",
-			args{
-				"table",
-				`CREATE TABLE ` + "`table`" + ` (
-  ` + "`id`" + ` int(11) NOT NULL AUTO_INCREMENT,
-  ` + "`data`" + ` blob,
-  ` + "`s`" + ` char(60) DEFAULT NULL,
-  PRIMARY KEY (` + "`id`" + `)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
-				map[string][]string{
-					"table": {"data"},
-				},
-			},
-		},
-		{
-			"manage create table with both binary and blob",
-			args{
-				"table",
-				`CREATE TABLE ` + "`table`" + ` (
-  ` + "`id`" + ` binary(16) NOT NULL,
-  ` + "`data`" + ` blob,
-  ` + "`content`" + ` longblob,
-  ` + "`s`" + ` char(60) DEFAULT NULL,
-  PRIMARY KEY (` + "`id`" + `)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
-				map[string][]string{
-					"table": {"id", "data", "content"},
-				},
-			},
-		},
-		{
-			"manage create table with tinyblob, mediumblob, and longblob",
-			args{
-				"table",
-				`CREATE TABLE ` + "`table`" + ` (
-  ` + "`id`" + ` int(11) NOT NULL AUTO_INCREMENT,
-  ` + "`tiny_data`" + ` tinyblob,
-  ` + "`medium_data`" + ` mediumblob,
-  ` + "`long_data`" + ` longblob,
-  PRIMARY KEY (` + "`id`" + `)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
-				map[string][]string{
-					"table": {"tiny_data", "medium_data", "long_data"},
-				},
-			},
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "name"}}, primaryKey("users", "id")...)
+
+	// rows are read in the same order on every run
+	mock.ExpectQuery("SELECT `id`, 'faker.Person\\(\\).Name\\(\\)' AS `name`, `users`.`id` FROM `users` ORDER BY `users`.`id`$").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name", "id"}).
+				AddRow(1, "faker.Person().Name()", 1).
+				AddRow(2, "faker.Person().Name()", 2),
+		)
+
+	// the column gets its own service, created once
+	gen.EXPECT().ForColumn("users", "name").Return(names)
+	generated := []string{"Mary", "John"}
+	names.EXPECT().Compile("faker.Person().Name()").Return(generator.FakerFunc(func(original []byte) (string, error) {
+		name := generated[0]
+		generated = generated[1:]
+
+		return name, nil
+	}), nil)
+
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "INSERT INTO `users` (`id`, `name`) VALUES\n( '1', 'Mary' ),\n( '2', 'John' );\n", buffer.String())
+}
+
+func TestMySQLDumpTableDataWithInvalidFaker(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{"users": {"name": "faker.Person().Nome()"}}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "name"}})
+
+	// the rule fails before any row is read
+	err := dumper.dumpTableData(new(bytes.Buffer), "users")
+	assert.EqualError(
+		t,
+		err,
+		"column `name` of table users: faker expression \"faker.Person().Nome()\", at position 16: "+
+			"faker.Person has no method Nome",
+	)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDumpTableDataWithTransforms(t *testing.T) {
+	db, mock := getDB(t)
+	buffer := bytes.NewBuffer(make([]byte, 0))
+
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{
+		"users": {
+			"name":  "go:keep_if_empty|keep_first(1)",
+			"zip":   "go:keep_first(2)",
+			"token": "go:hash",
+			"notes": "go:default",
 		},
 	}
-	for _, tt := range tests {
-		t.Run(
-			tt.name, func(t *testing.T) {
-				d := getInternalMySQLInstance(db, nil)
-				d.parseBinaryRelations(tt.args.table, tt.args.createTable)
-				assert.Equal(t, d.mapBins, tt.args.expectedMap)
-			},
-		)
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "name", "zip int", "token", "notes"}})
+
+	// the original values are selected, for the transforms to see them
+	mock.ExpectQuery("SELECT `id`, `name`, `zip`, `token`, `notes` FROM `users`$").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT", 0),
+			sqlmock.NewColumn("name").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("zip").OfType("INT", 0),
+			sqlmock.NewColumn("token").OfType("INT", 0),
+			sqlmock.NewColumn("notes").OfType("TEXT", ""),
+		).
+			AddRow(1, "O'Neil", 1234, 42, "secret").
+			AddRow(2, "", 1234, nil, nil),
+	)
+
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		"INSERT INTO `users` (`id`, `name`, `zip`, `token`, `notes`) VALUES\n"+
+			"( 1, 'O', 12, '73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049', DEFAULT ),\n"+
+			"( 2, '', 12, NULL, DEFAULT );\n",
+		buffer.String(),
+	)
+}
+
+func TestMySQLGetTransformedValueMasksNumbersAsStrings(t *testing.T) {
+	db, _ := getDB(t)
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+
+	transform, err := generator.ParseTransform("go:mask(keep_last=4)")
+	assert.Nil(t, err)
+
+	card := sql.RawBytes("4111111111111111")
+	val, err := dumper.getTransformedValue(transform, &card, "BIGINT", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "'************1111'", val)
+}
+
+func TestMySQLDumpTableDataWithInvalidTransform(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{"users": {"name": "go:scramble"}}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "name"}})
+
+	err := dumper.dumpTableData(new(bytes.Buffer), "users")
+	assert.EqualError(t, err, "column `name` of table users: transform \"go:scramble\": unknown step scramble")
+}
+
+func TestMySQLDumpTableDataWithTemplates(t *testing.T) {
+	db, mock := getDB(t)
+	buffer := bytes.NewBuffer(make([]byte, 0))
+
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{
+		"users": {
+			"login": "tmpl:TEST-{{seq}}",
+			"name":  "tmpl:{{slug .Value}}@{{.Table}}.test",
+			"notes": "tmpl:{{.Column | upper}} of {{.Value}}",
+		},
 	}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "login", "name", "notes"}})
+
+	// the original values are selected, for the templates to see them
+	mock.ExpectQuery("SELECT `id`, `login`, `name`, `notes` FROM `users`$").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT", 0),
+			sqlmock.NewColumn("login").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("name").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("notes").OfType("TEXT", ""),
+		).
+			AddRow(1, "jo", "Zé O'Neil", "a").
+			AddRow(2, "ana", "Ana", nil),
+	)
+
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		"INSERT INTO `users` (`id`, `login`, `name`, `notes`) VALUES\n"+
+			"( 1, 'TEST-1', 'ze-o-neil@users.test', 'NOTES of a' ),\n"+
+			"( 2, 'TEST-2', 'ana@users.test', NULL );\n",
+		buffer.String(),
+	)
 }
 
-func Test_mySQL_removeGeneratedColumns(t *testing.T) {
+func TestMySQLDumpTableDataWithInvalidTemplate(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{"users": {"name": "tmpl:{{shout .Value}}"}}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "name"}})
+
+	err := dumper.dumpTableData(new(bytes.Buffer), "users")
+	assert.EqualError(
+		t,
+		err,
+		"column `name` of table users: template \"tmpl:{{shout .Value}}\": "+
+			"template: tmpl:1: function \"shout\" not defined",
+	)
+}
+
+func TestMySQLDumpTableDataHandlingErrorFromSelectAllDataFor(t *testing.T) {
+	db, mock := getDB(t)
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	dumper := getInternalMySQLInstance(db, nil)
+	err := errors.New("fail")
+	mock.ExpectQuery("FROM information_schema.COLUMNS").WillReturnError(err)
+	assert.Equal(t, err, dumper.dumpTableData(buffer, "table"))
+}
+
+func Test_mySQL_excludeGeneratedColumns(t *testing.T) {
 	db, _ := getDB(t)
 	type args struct {
 		table         string
+		columns       []string
 		createTable   string
 		strippedTable string
-		expectedMap   map[string][]string
 	}
 	tests := []struct {
 		name string
@@ -385,6 +514,7 @@ func Test_mySQL_removeGeneratedColumns(t *testing.T) {
 			"removes successfully generated columns",
 			args{
 				"table",
+				[]string{"id binary", "s char", "reversed varchar STORED GENERATED"},
 				`CREATE TABLE ` + "`table`" + ` (
   ` + "`id`" + ` binary(16) NOT NULL AUTO_INCREMENT,
   ` + "`s`" + ` char(60) DEFAULT NULL,
@@ -397,142 +527,62 @@ func Test_mySQL_removeGeneratedColumns(t *testing.T) {
   ` + "`s`" + ` char(60) DEFAULT NULL,
   PRIMARY KEY (` + "`id`" + `)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
-				map[string][]string{
-					"table": {"reversed"},
-				},
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(
-			tt.name, func(t *testing.T) {
-				d := getInternalMySQLInstance(db, nil)
-				d.excludeGeneratedColumns(tt.args.table, tt.args.createTable)
-				assert.Equal(t, d.mapExclusionColumns, tt.args.expectedMap)
-			},
-		)
-	}
-}
-
-func Test_mySQL_isColumnBinary(t *testing.T) {
-	db, _ := getDB(t)
-	type args struct {
-		table      string
-		columnName string
-		m          map[string][]string
-	}
-	tests := []struct {
-		name string
-		args args
-		want bool
-	}{
-		{
-			"should get true",
-			args{
-				"table",
-				"id",
-				map[string][]string{
-					"table": {"id"},
-				},
 			},
-			true,
 		},
 		{
-			"should get false",
+			"keeps columns whose default is generated",
 			args{
 				"table",
-				"potatoes",
-				map[string][]string{
-					"table": {"id"},
-				},
-			},
-			false,
-		},
-		{
-			"should get false",
-			args{
-				"cabbage",
-				"id",
-				map[string][]string{
-					"table": {"id"},
-				},
+				[]string{"id int", "created_at timestamp DEFAULT_GENERATED"},
+				`CREATE TABLE ` + "`table`" + ` (
+  ` + "`id`" + ` int NOT NULL,
+  ` + "`created_at`" + ` timestamp NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE ` + "`table`" + ` (
+  ` + "`id`" + ` int NOT NULL,
+  ` + "`created_at`" + ` timestamp NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 			},
-			false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(
 			tt.name, func(t *testing.T) {
 				d := getInternalMySQLInstance(db, nil)
-				d.mapBins = tt.args.m
-				assert.Equalf(
-					t,
-					tt.want,
-					d.isColumnBinary(tt.args.table, tt.args.columnName),
-					"isColumnBinary(%v, %v)",
-					tt.args.table,
-					tt.args.columnName,
-				)
+				d.schemas[""] = newTestSchema(map[string][]string{tt.args.table: tt.args.columns})
+				stripped, err := d.excludeGeneratedColumns(tt.args.table, tt.args.createTable)
+				assert.Nil(t, err)
+				assert.Equal(t, tt.args.strippedTable, stripped)
 			},
 		)
 	}
 }
 
-func Test_mySQL_isColumnExcluded(t *testing.T) {
+func Test_mySQL_isColumnBinary(t *testing.T) {
 	db, _ := getDB(t)
 	type args struct {
 		table      string
 		columnName string
-		m          map[string][]string
 	}
 	tests := []struct {
 		name string
 		args args
 		want bool
 	}{
-		{
-			"should get true",
-			args{
-				"table",
-				"id",
-				map[string][]string{
-					"table": {"id"},
-				},
-			},
-			true,
-		},
-		{
-			"should get false",
-			args{
-				"table",
-				"potatoes",
-				map[string][]string{
-					"table": {"id"},
-				},
-			},
-			false,
-		},
-		{
-			"should get false",
-			args{
-				"cabbage",
-				"id",
-				map[string][]string{
-					"table": {"id"},
-				},
-			},
-			false,
-		},
+		{"should get true", args{"table", "id"}, true},
+		{"should get true for quoted blobs", args{"table", "`Data`"}, true},
+		{"should get false", args{"table", "potatoes"}, false},
+		{"should get false", args{"cabbage", "id"}, false},
 	}
 	for _, tt := range tests {
 		t.Run(
 			tt.name, func(t *testing.T) {
 				d := getInternalMySQLInstance(db, nil)
-				d.mapExclusionColumns = tt.args.m
+				d.schemas[""] = newTestSchema(map[string][]string{"table": {"id binary", "data mediumblob", "potatoes"}})
 				assert.Equalf(
 					t,
 					tt.want,
-					d.isColumnExcluded(tt.args.table, tt.args.columnName),
+					d.isColumnBinary(tt.args.table, tt.args.columnName),
 					"isColumnBinary(%v, %v)",
 					tt.args.table,
 					tt.args.columnName,
@@ -567,7 +617,9 @@ func Test_mySQL_ignoresTable(t *testing.T) {
 		t.Error(err)
 	}
 
-	if b.String() != "SET FOREIGN_KEY_CHECKS = 1;\n" {
+	// the header is written anyway, as the trailer restores the SQL mode it saved
+	if b.String() != "SET NAMES utf8;\nSET FOREIGN_KEY_CHECKS = 0;\n"+setSQLModeStatement+
+		"SET FOREIGN_KEY_CHECKS = 1;\n"+restoreSQLModeStatement {
 		t.Error("No tables should be dumped")
 	}
 }
@@ -661,6 +713,7 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 		col        *sql.RawBytes
 		table      string
 		columnName string
+		columnType string
 		setupFunc  func(*mySQL)
 	}
 	tests := []struct {
@@ -686,9 +739,7 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 				columnName: "data",
 				setupFunc: func(d *mySQL) {
 					d.shouldHexBins = true
-					d.mapBins = map[string][]string{
-						"test_table": {"data"},
-					}
+					d.schemas[""] = newTestSchema(map[string][]string{"test_table": {"data blob"}})
 				},
 			},
 			want: "0x68656c6c6f", // "hello" in hex
@@ -701,9 +752,7 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 				columnName: "data",
 				setupFunc: func(d *mySQL) {
 					d.shouldHexBins = true
-					d.mapBins = map[string][]string{
-						"test_table": {"data"},
-					}
+					d.schemas[""] = newTestSchema(map[string][]string{"test_table": {"data blob"}})
 				},
 			},
 			want: "NULL",
@@ -716,9 +765,7 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 				columnName: "blob_data",
 				setupFunc: func(d *mySQL) {
 					d.shouldHexBins = true
-					d.mapBins = map[string][]string{
-						"test_table": {"blob_data"},
-					}
+					d.schemas[""] = newTestSchema(map[string][]string{"test_table": {"blob_data blob"}})
 				},
 			},
 			want: "0x010203ff",
@@ -731,9 +778,7 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 				columnName: "name",
 				setupFunc: func(d *mySQL) {
 					d.shouldHexBins = true
-					d.mapBins = map[string][]string{
-						"test_table": {"data"}, // different column
-					}
+					d.schemas[""] = newTestSchema(map[string][]string{"test_table": {"data blob"}}) // different column
 				},
 			},
 			want: "'hello'",
@@ -746,9 +791,7 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 				columnName: "data",
 				setupFunc: func(d *mySQL) {
 					d.shouldHexBins = false
-					d.mapBins = map[string][]string{
-						"test_table": {"data"},
-					}
+					d.schemas[""] = newTestSchema(map[string][]string{"test_table": {"data blob"}})
 				},
 			},
 			want: "'test'",
@@ -761,13 +804,101 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 				columnName: "data",
 				setupFunc: func(d *mySQL) {
 					d.shouldHexBins = true
-					d.mapBins = map[string][]string{
-						"test_table": {"data"},
-					}
+					d.schemas[""] = newTestSchema(map[string][]string{"test_table": {"data blob"}})
 				},
 			},
 			want: "0x00017f80ff",
 		},
+		{
+			name: "integer column is not quoted",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("42"); return &b }(),
+				table:      "test_table",
+				columnName: "id",
+				columnType: "INT",
+			},
+			want: "42",
+		},
+		{
+			name: "unsigned big integer column is not quoted",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("18446744073709551615"); return &b }(),
+				table:      "test_table",
+				columnName: "id",
+				columnType: "UNSIGNED BIGINT",
+			},
+			want: "18446744073709551615",
+		},
+		{
+			name: "decimal column is not quoted",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("-12.50"); return &b }(),
+				table:      "test_table",
+				columnName: "price",
+				columnType: "DECIMAL",
+			},
+			want: "-12.50",
+		},
+		{
+			name: "bit column is written as a bit literal",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("\x00\x05"); return &b }(),
+				table:      "test_table",
+				columnName: "flags",
+				columnType: "BIT",
+			},
+			want: "b'101'",
+		},
+		{
+			name: "bit column without any bit set",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("\x00"); return &b }(),
+				table:      "test_table",
+				columnName: "flags",
+				columnType: "BIT",
+			},
+			want: "b'0'",
+		},
+		{
+			name: "varbinary column is hex encoded without hex-encode",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("\x01\x02"); return &b }(),
+				table:      "test_table",
+				columnName: "token",
+				columnType: "VARBINARY",
+			},
+			want: "0x0102",
+		},
+		{
+			name: "empty blob column is an empty string",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes(""); return &b }(),
+				table:      "test_table",
+				columnName: "data",
+				columnType: "BLOB",
+			},
+			want: "''",
+		},
+		{
+			name: "zero date is kept quoted",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("0000-00-00 00:00:00"); return &b }(),
+				table:      "test_table",
+				columnName: "created_at",
+				columnType: "DATETIME",
+			},
+			want: "'0000-00-00 00:00:00'",
+		},
+		{
+			name: "varchar column is quoted and escaped",
+			args: args{
+				col:        func() *sql.RawBytes { b := sql.RawBytes("it's"); return &b }(),
+				table:      "test_table",
+				columnName: "name",
+				columnType: "VARCHAR",
+			},
+			want: "'it\\'s'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -776,7 +907,7 @@ func Test_mySQL_getProperEscapedValue(t *testing.T) {
 			if tt.args.setupFunc != nil {
 				tt.args.setupFunc(d)
 			}
-			got := d.getProperEscapedValue(tt.args.col, tt.args.table, tt.args.columnName)
+			got := d.getProperEscapedValue(tt.args.col, tt.args.table, tt.args.columnName, tt.args.columnType)
 			assert.Equal(t, tt.want, got, "getProperEscapedValue() = %v, want %v", got, tt.want)
 		})
 	}
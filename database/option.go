@@ -3,17 +3,25 @@ package database
 import (
 	"errors"
 	"strconv"
+
+	"github.com/doutorfinancas/go-mad/core"
 )
 
 type Option struct {
-	key   string
-	value string
+	key    string
+	value  string
+	values []string
 }
 
 func OptionValue(key, value string) Option {
 	return Option{key: key, value: value}
 }
 
+// OptionValues is an option taking a list, whose values are kept as they are, commas included
+func OptionValues(key string, values ...string) Option {
+	return Option{key: key, values: values}
+}
+
 func parseMysqlOptions(m *mySQL, options []Option) error {
 	for _, v := range options {
 		switch v.key {
@@ -31,8 +39,16 @@ func parseMysqlOptions(m *mySQL, options []Option) error {
 			m.ignoreGenerated = true
 		case "dump-trigger":
 			m.dumpTrigger = true
+		case "routines":
+			m.dumpRoutine = true
+		case "events":
+			m.dumpEvent = true
+		case "disable-events":
+			m.disableEvents = true
 		case "skip-definer":
 			m.skipDefiner = true
+		case "skip-views":
+			m.dumpViews = false
 		case "insert-into-limit":
 			i, err := strconv.Atoi(v.value)
 			if err != nil {
@@ -42,6 +58,60 @@ func parseMysqlOptions(m *mySQL, options []Option) error {
 			m.extendedInsertLimit = i
 		case "trigger-delimiter":
 			m.triggerDelimiter = v.value
+		case "databases":
+			m.databases = v.values
+		case "all-databases":
+			m.allDatabases = true
+		case "seed":
+			seed, err := strconv.ParseInt(v.value, 10, 64)
+			if err != nil {
+				return err
+			}
+			m.seed = &seed
+		case "deterministic":
+			m.deterministic = true
+		case "locale":
+			m.locale = v.value
+		case "subset":
+			m.subset = true
+		case "master-data":
+			mode, err := strconv.Atoi(v.value)
+			if err != nil {
+				return err
+			}
+			if mode != MasterDataStatement && mode != MasterDataComment {
+				return errors.New("master-data must be 1 or 2")
+			}
+			m.masterData = mode
+		case "chunk-size":
+			size, err := strconv.Atoi(v.value)
+			if err != nil {
+				return err
+			}
+			if size < 1 {
+				return errors.New("chunk-size must be at least 1")
+			}
+			m.chunkSize = size
+		case "compress":
+			if err := core.ValidateCompression(v.value); err != nil {
+				return err
+			}
+			m.compression = v.value
+		case "checkpoint":
+			m.checkpointPath = v.value
+		case "resume":
+			m.resume = true
+		case "parallel":
+			i, err := strconv.Atoi(v.value)
+			if err != nil {
+				return err
+			}
+
+			if i < 1 {
+				return errors.New("parallel requires at least one worker")
+			}
+
+			m.parallel = i
 		default:
 			return errors.New("unknown option")
 		}
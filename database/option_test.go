@@ -7,6 +7,8 @@ import (
 )
 
 func TestOption(t *testing.T) {
+	seed := int64(42)
+
 	var testCases = []struct {
 		options []Option
 		mysql   *mySQL
@@ -23,6 +25,15 @@ func TestOption(t *testing.T) {
 				OptionValue("hex-encode", ""),
 				OptionValue("ignore-generated", ""),
 				OptionValue("insert-into-limit", "99"),
+				OptionValue("parallel", "4"),
+				OptionValue("compress", "zstd"),
+				OptionValue("chunk-size", "5000"),
+				OptionValue("master-data", "2"),
+				OptionValue("subset", ""),
+				OptionValue("deterministic", ""),
+				OptionValue("seed", "42"),
+				OptionValue("locale", "pt"),
+				OptionValues("databases", "shop", "a,b"),
 			},
 			&mySQL{},
 			&mySQL{
@@ -33,6 +44,15 @@ func TestOption(t *testing.T) {
 				extendedInsertLimit: 99,
 				shouldHexBins:       true,
 				ignoreGenerated:     true,
+				parallel:            4,
+				compression:         "zstd",
+				chunkSize:           5000,
+				masterData:          MasterDataComment,
+				subset:              true,
+				deterministic:       true,
+				seed:                &seed,
+				locale:              "pt",
+				databases:           []string{"shop", "a,b"},
 			},
 			"switch all cases",
 			false,
@@ -62,6 +82,51 @@ func TestOption(t *testing.T) {
 			"trying to pass something weird into limit",
 			true,
 		},
+		{
+			[]Option{
+				OptionValue("parallel", "0"),
+			},
+			&mySQL{},
+			&mySQL{},
+			"parallel needs at least one worker",
+			true,
+		},
+		{
+			[]Option{
+				OptionValue("master-data", "3"),
+			},
+			&mySQL{},
+			&mySQL{},
+			"master data is either statements or comments",
+			true,
+		},
+		{
+			[]Option{
+				OptionValue("chunk-size", "0"),
+			},
+			&mySQL{},
+			&mySQL{},
+			"chunks need at least one row",
+			true,
+		},
+		{
+			[]Option{
+				OptionValue("seed", "abc"),
+			},
+			&mySQL{},
+			&mySQL{},
+			"seeds are integers",
+			true,
+		},
+		{
+			[]Option{
+				OptionValue("compress", "lz4"),
+			},
+			&mySQL{},
+			&mySQL{},
+			"unsupported compression",
+			true,
+		},
 		{
 			[]Option{
 				OptionValue("insert-into-limit", ""),
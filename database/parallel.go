@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/doutorfinancas/go-mad/generator"
+	"go.uber.org/zap"
+)
+
+type tableResult struct {
+	file  *os.File
+	entry ManifestTable
+	err   error
+}
+
+// tableDumper dumps a single table using worker, which is bound to its own connection
+type tableDumper func(worker *mySQL, table string) tableResult
+
+// dumpTablesInParallel dumps tables using a pool of workers, each one running on
+// its own connection. Tables are scheduled largest first, but each one is staged
+// in a temporary file and copied to w in the original table order, so the
+// resulting dump is exactly the same as the sequential one
+func (d *mySQL) dumpTablesInParallel(w io.Writer, tables []string) error {
+	var pending []string
+	for _, table := range tables {
+		if !d.isTableIgnored(table) && !d.isTableDone(table) {
+			pending = append(pending, table)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, wait, err := d.startWorkers(ctx, pending, (*mySQL).dumpTableToFile)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range pending {
+		res := <-results[table]
+		if res.err == nil {
+			res.err = d.copyTableResult(w, res.file)
+		}
+
+		if res.err == nil {
+			res.err = d.checkpointTableDone(table)
+		}
+
+		if res.err != nil {
+			cancel()
+			wait()
+			d.discardTableResults(results)
+
+			return res.err
+		}
+	}
+
+	wait()
+
+	return nil
+}
+
+// startWorkers schedules every table in pending to a pool of workers, largest first.
+// The result of each table is delivered in its own channel, the returned func
+// waits for every worker to finish
+func (d *mySQL) startWorkers(ctx context.Context, pending []string, dump tableDumper) (
+	map[string]chan tableResult,
+	func(),
+	error,
+) {
+	// workers share the schema model, which must be loaded beforehand
+	if _, err := d.getSchema(); err != nil {
+		return nil, nil, err
+	}
+
+	schedule, err := d.scheduleBySize(pending)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make(map[string]chan tableResult, len(pending))
+	for _, table := range pending {
+		results[table] = make(chan tableResult, 1)
+	}
+
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		for _, table := range schedule {
+			select {
+			case jobs <- table:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	workers := d.parallel
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.runWorker(ctx, jobs, results, dump)
+		}()
+	}
+
+	return results, wg.Wait, nil
+}
+
+// runWorker dumps every table it receives, holding a dedicated
+// connection for the whole lifetime of the worker
+func (d *mySQL) runWorker(
+	ctx context.Context,
+	jobs <-chan string,
+	results map[string]chan tableResult,
+	dump tableDumper,
+) {
+	conn, release, err := d.workerConn(ctx)
+	if err == nil {
+		defer release()
+	}
+
+	worker := d.newWorker(conn)
+	for table := range jobs {
+		if err != nil {
+			results[table] <- tableResult{err: err}
+			continue
+		}
+
+		results[table] <- dump(worker, table)
+	}
+}
+
+// newWorker returns a copy of the dumper bound to conn, with its own
+// per table caches so workers never share mutable state
+func (d *mySQL) newWorker(conn connection) *mySQL {
+	worker := *d
+	worker.conn = conn
+	// the snapshot belongs to the dumper, the worker only borrows one of its connections
+	worker.snapshot = nil
+	worker.checkpoint = nil
+	worker.generators = make(map[string]generator.Service)
+
+	return &worker
+}
+
+func (d *mySQL) dumpTableToFile(table string) tableResult {
+	f, err := os.CreateTemp("", "go-mad-*.sql")
+	if err != nil {
+		return tableResult{err: err}
+	}
+
+	if err = d.dumpTable(f, table); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		removeTableFile(f)
+		return tableResult{err: err}
+	}
+
+	return tableResult{file: f}
+}
+
+func (d *mySQL) copyTableResult(w io.Writer, f *os.File) error {
+	defer removeTableFile(f)
+
+	_, err := io.Copy(w, f)
+
+	return err
+}
+
+// discardTableResults removes every temporary file that was already produced
+// but will never be copied, since the dump was aborted
+func (d *mySQL) discardTableResults(results map[string]chan tableResult) {
+	for _, ch := range results {
+		select {
+		case res := <-ch:
+			if res.file != nil {
+				removeTableFile(res.file)
+			}
+		default:
+		}
+	}
+}
+
+func removeTableFile(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// scheduleBySize sorts tables by their data length, largest first, so the
+// biggest tables do not end up being the last ones to start
+func (d *mySQL) scheduleBySize(tables []string) ([]string, error) {
+	sizes, err := d.getTableSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := make([]string, len(tables))
+	copy(schedule, tables)
+
+	sort.SliceStable(
+		schedule, func(i, j int) bool {
+			return sizes[strings.ToLower(schedule[i])] > sizes[strings.ToLower(schedule[j])]
+		},
+	)
+
+	return schedule, nil
+}
+
+func (d *mySQL) getTableSizes() (map[string]uint64, error) {
+	sizes := make(map[string]uint64)
+
+	rows, err := d.conn.QueryContext(
+		context.Background(),
+		"SELECT TABLE_NAME, COALESCE(DATA_LENGTH, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = "+d.schemaExpression(),
+	)
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return sizes, a
+	}
+
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("internal", "failed to close rows while getting table sizes"),
+			)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var tableName string
+		var size uint64
+
+		if dErr := rows.Scan(&tableName, &size); dErr != nil {
+			return sizes, dErr
+		}
+
+		sizes[strings.ToLower(tableName)] = size
+	}
+
+	return sizes, rows.Err()
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func expectTableDump(mock sqlmock.Sqlmock, table, value string) {
+	mock.ExpectQuery("SHOW CREATE TABLE `" + table + "`").WillReturnRows(
+		sqlmock.NewRows([]string{"Table", "Create Table"}).
+			AddRow(table, "CREATE TABLE `"+table+"` (`id` int(11) NOT NULL)"),
+	)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `" + table + "`").WillReturnRows(
+		sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1),
+	)
+	mock.ExpectQuery("SELECT `id` FROM `" + table + "`").WillReturnRows(
+		sqlmock.NewRows([]string{"id"}).AddRow(value),
+	)
+}
+
+func Test_mySQL_dumpsTablesInParallel(t *testing.T) {
+	db, mock := getDB(t)
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectQuery("SHOW FULL TABLES").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+			AddRow("large", "BASE TABLE").
+			AddRow("medium", "BASE TABLE").
+			AddRow("small", "BASE TABLE"),
+	)
+	mock.ExpectQuery("SELECT TABLE_NAME, COALESCE\\(DATA_LENGTH, 0\\) FROM information_schema.TABLES").WillReturnRows(
+		sqlmock.NewRows([]string{"TABLE_NAME", "DATA_LENGTH"}).
+			AddRow("small", 10).
+			AddRow("medium", 100).
+			AddRow("large", 1000),
+	)
+	expectSchema(mock, map[string][]string{"large": {"id int"}, "medium": {"id int"}, "small": {"id int"}})
+	expectTableDump(mock, "large", "1")
+	expectTableDump(mock, "medium", "2")
+	expectTableDump(mock, "small", "3")
+
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.log = zap.NewNop()
+	dumper.lockTables = false
+	dumper.parallel = 2
+
+	b := new(strings.Builder)
+	assert.Nil(t, dumper.Dump(b))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "SET NAMES utf8;\nSET FOREIGN_KEY_CHECKS = 0;\n"))
+	assert.True(t, strings.HasSuffix(out, "SET FOREIGN_KEY_CHECKS = 1;\n"+restoreSQLModeStatement))
+
+	large := strings.Index(out, "Structure for table `large`")
+	medium := strings.Index(out, "Structure for table `medium`")
+	small := strings.Index(out, "Structure for table `small`")
+	assert.True(t, large < medium && medium < small, "tables should keep their original order")
+
+	// every table must be a contiguous block, data right after its own structure
+	assert.True(t, strings.Index(out, "INSERT INTO `large`") < medium)
+	assert.True(t, strings.Index(out, "INSERT INTO `medium`") < small)
+	assert.Contains(t, out[small:], "INSERT INTO `small` (`id`) VALUES\n( '3' );")
+}
+
+func Test_mySQL_scheduleBySize(t *testing.T) {
+	db, mock := getDB(t)
+	mock.ExpectQuery("information_schema.TABLES").WillReturnRows(
+		sqlmock.NewRows([]string{"TABLE_NAME", "DATA_LENGTH"}).
+			AddRow("a", 10).
+			AddRow("B", 300).
+			AddRow("c", 200),
+	)
+
+	dumper := getInternalMySQLInstance(db, nil)
+	schedule, err := dumper.scheduleBySize([]string{"a", "B", "c", "d"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"B", "c", "a", "d"}, schedule)
+}
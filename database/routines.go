@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/gobwas/glob"
+	"go.uber.org/zap"
+)
+
+// DefaultRoutineDelimiter wraps routine and event bodies when no trigger delimiter is given
+const DefaultRoutineDelimiter = ";;"
+
+type routine struct {
+	kind string
+	name string
+}
+
+func (d *mySQL) SetRoutineFilter(ignore []string) error {
+	d.routineFilter = make([]glob.Glob, 0, len(ignore))
+
+	for _, query := range ignore {
+		g, err := glob.Compile(query)
+		if err != nil {
+			return err
+		}
+
+		d.routineFilter = append(d.routineFilter, g)
+	}
+
+	return nil
+}
+
+func (d *mySQL) isRoutineIgnored(name string) bool {
+	for _, g := range d.routineFilter {
+		if g.Match(name) || (d.schema != "" && g.Match(d.schema+"."+name)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routineDelimiter is the delimiter used around bodies that contain
+// several statements, which cannot be terminated by a semicolon
+func (d *mySQL) routineDelimiter() string {
+	if d.triggerDelimiter != "" {
+		return d.triggerDelimiter
+	}
+
+	return DefaultRoutineDelimiter
+}
+
+// writeDelimited writes ddl wrapped in a DELIMITER block, restoring the sql_mode
+// it was created with, since it affects how the body is parsed
+func (d *mySQL) writeDelimited(w io.Writer, sqlMode, ddl string) error {
+	delimiter := d.routineDelimiter()
+
+	s := "SET @saved_sql_mode = @@sql_mode;\n"
+	s += fmt.Sprintf("SET sql_mode = '%s';\n", escape(sqlMode))
+	s += fmt.Sprintf("DELIMITER %s\n", delimiter)
+	s += fmt.Sprintf("%s %s\n", ddl, delimiter)
+	s += "DELIMITER ;\n"
+	s += "SET sql_mode = @saved_sql_mode;\n"
+
+	_, err := io.WriteString(w, s)
+
+	return err
+}
+
+func (d *mySQL) dumpRoutines(w io.Writer) error {
+	routines, err := d.getRoutines()
+	if err != nil {
+		return err
+	}
+
+	for _, r := range routines {
+		if d.isRoutineIgnored(r.name) {
+			continue
+		}
+
+		sqlMode, ddl, err := d.getRoutine(r)
+		if err != nil {
+			return err
+		}
+
+		s := fmt.Sprintf("\n--\n-- %s `%s`\n--\n\n", routineTitle(r.kind), r.name)
+		s += fmt.Sprintf("DROP %s IF EXISTS `%s`;\n", r.kind, r.name)
+		if _, err = io.WriteString(w, s); err != nil {
+			return err
+		}
+
+		if err = d.writeDelimited(w, sqlMode, ddl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func routineTitle(kind string) string {
+	if kind == "FUNCTION" {
+		return "Function"
+	}
+
+	return "Procedure"
+}
+
+func (d *mySQL) getRoutines() ([]routine, error) {
+	routines := make([]routine, 0)
+
+	rows, err := d.conn.QueryContext(
+		context.Background(),
+		"SELECT ROUTINE_TYPE, ROUTINE_NAME FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = "+
+			d.schemaExpression()+" ORDER BY ROUTINE_TYPE, ROUTINE_NAME",
+	)
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return routines, a
+	}
+
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("internal", "failed to close rows while getting routines"),
+			)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var r routine
+
+		if dErr := rows.Scan(&r.kind, &r.name); dErr != nil {
+			return routines, dErr
+		}
+
+		routines = append(routines, r)
+	}
+
+	return routines, nil
+}
+
+func (d *mySQL) getRoutine(r routine) (sqlMode, ddl string, err error) {
+	var name, unknown string
+	var create sql.NullString
+
+	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE %s %s", r.kind, d.qualify(r.name)))
+	if err = row.Scan(&name, &sqlMode, &create, &unknown, &unknown, &unknown); err != nil {
+		return "", "", err
+	}
+
+	// the body is only visible to the definer or to users with enough privileges
+	if !create.Valid {
+		return "", "", fmt.Errorf("not allowed to read the definition of %s `%s`", r.kind, r.name)
+	}
+
+	return sqlMode, d.stripDefiner(create.String), nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mySQL_dumpsRoutines(t *testing.T) {
+	db, mock := getDB(t)
+
+	mock.ExpectQuery("SHOW FULL TABLES").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}),
+	)
+	mock.ExpectQuery("SELECT ROUTINE_TYPE, ROUTINE_NAME FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = DATABASE\\(\\)").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"ROUTINE_TYPE", "ROUTINE_NAME"}).
+				AddRow("FUNCTION", "full_name").
+				AddRow("PROCEDURE", "tmp_cleanup").
+				AddRow("PROCEDURE", "touch_users"),
+		)
+	mock.ExpectQuery("SHOW CREATE FUNCTION `full_name`").WillReturnRows(
+		sqlmock.NewRows([]string{"Function", "sql_mode", "Create Function", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow(
+				"full_name",
+				"STRICT_TRANS_TABLES",
+				"CREATE DEFINER=`root`@`%` FUNCTION `full_name`(f VARCHAR(20), l VARCHAR(20)) RETURNS varchar(41)\n"+
+					"DETERMINISTIC\nBEGIN\n  RETURN CONCAT(f, ' ', l);\nEND",
+				"utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci",
+			),
+	)
+	mock.ExpectQuery("SHOW CREATE PROCEDURE `touch_users`").WillReturnRows(
+		sqlmock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow(
+				"touch_users",
+				"",
+				"CREATE DEFINER=`root`@`%` PROCEDURE `touch_users`()\nBEGIN\n  UPDATE users SET updated_at = NOW();\nEND",
+				"utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci",
+			),
+	)
+
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.dumpRoutine = true
+	dumper.skipDefiner = true
+	assert.Nil(t, dumper.SetRoutineFilter([]string{"tmp_*"}))
+
+	b := new(strings.Builder)
+	assert.Nil(t, dumper.Dump(b))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	out := b.String()
+	assert.NotContains(t, out, "tmp_cleanup")
+	assert.NotContains(t, out, "DEFINER=")
+	assert.Contains(
+		t,
+		out,
+		"DROP FUNCTION IF EXISTS `full_name`;\n"+
+			"SET @saved_sql_mode = @@sql_mode;\n"+
+			"SET sql_mode = 'STRICT_TRANS_TABLES';\n"+
+			"DELIMITER ;;\n"+
+			"CREATE FUNCTION `full_name`(f VARCHAR(20), l VARCHAR(20)) RETURNS varchar(41)\n"+
+			"DETERMINISTIC\nBEGIN\n  RETURN CONCAT(f, ' ', l);\nEND ;;\n"+
+			"DELIMITER ;\n"+
+			"SET sql_mode = @saved_sql_mode;\n",
+	)
+	assert.Contains(t, out, "-- Procedure `touch_users`")
+	assert.Contains(t, out, "DROP PROCEDURE IF EXISTS `touch_users`;\n")
+}
+
+func Test_mySQL_getRoutineWithoutPrivileges(t *testing.T) {
+	db, mock := getDB(t)
+	mock.ExpectQuery("SHOW CREATE PROCEDURE `secret`").WillReturnRows(
+		sqlmock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure", "character_set_client", "collation_connection", "Database Collation"}).
+			AddRow("secret", "", nil, "utf8mb4", "utf8mb4_general_ci", "utf8mb4_general_ci"),
+	)
+
+	dumper := getInternalMySQLInstance(db, nil)
+	_, _, err := dumper.getRoutine(routine{kind: "PROCEDURE", name: "secret"})
+	assert.EqualError(t, err, "not allowed to read the definition of PROCEDURE `secret`")
+}
+
+func Test_mySQL_routineDelimiter(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	assert.Equal(t, DefaultRoutineDelimiter, dumper.routineDelimiter())
+
+	dumper.triggerDelimiter = "$$"
+	assert.Equal(t, "$$", dumper.routineDelimiter())
+
+	assert.NotNil(t, dumper.SetRoutineFilter([]string{"[broken"}))
+}
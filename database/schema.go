@@ -0,0 +1,248 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Schema is the structure of a database, as read from information_schema
+type Schema struct {
+	Name string
+	// Tables lists every table and view, ordered by name
+	Tables []*Table
+	tables map[string]*Table
+}
+
+// Table is a table or a view, along with its columns and keys
+type Table struct {
+	Name string
+	// Columns lists every column in ordinal order, invisible ones included
+	Columns     []*Column
+	PrimaryKey  []string
+	UniqueKeys  map[string][]string
+	ForeignKeys []ForeignKey
+	columns     map[string]*Column
+}
+
+// Column is a single column of a table
+type Column struct {
+	Name string
+	// DataType is the bare type, such as varchar, and ColumnType the full one, such as varchar(255)
+	DataType   string
+	ColumnType string
+	// Length is the maximum length in characters of string columns, and 0 for any other type
+	Length    int64
+	Nullable  bool
+	Generated bool
+	Invisible bool
+	// Key is PRI, UNI or MUL when the column is part of an index, and empty otherwise
+	Key     string
+	Charset string
+}
+
+// ForeignKey is a possibly composite foreign key, from the columns of its table to the ones of another table
+type ForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedSchema  string
+	ReferencedTable   string
+	ReferencedColumns []string
+}
+
+// Table returns the table or view called name, whatever its case
+func (s *Schema) Table(name string) (*Table, bool) {
+	t, ok := s.tables[strings.ToLower(name)]
+	return t, ok
+}
+
+// Column returns the column called name, whatever its case
+func (t *Table) Column(name string) (*Column, bool) {
+	c, ok := t.columns[strings.ToLower(name)]
+	return c, ok
+}
+
+// IsBinary tells whether the column holds bytes rather than characters
+func (c *Column) IsBinary() bool {
+	switch c.DataType {
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsInteger tells whether the column holds whole numbers
+func (c *Column) IsInteger() bool {
+	switch c.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsUnsigned tells whether the numbers of the column cannot be negative
+func (c *Column) IsUnsigned() bool {
+	return strings.Contains(c.ColumnType, "unsigned")
+}
+
+// getSchema returns the model of the schema currently being dumped, which is only loaded once.
+// It must be loaded before parallel workers start, since they share it
+func (d *mySQL) getSchema() (*Schema, error) {
+	if s, ok := d.schemas[d.schema]; ok {
+		return s, nil
+	}
+
+	s, err := d.loadSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	d.schemas[d.schema] = s
+
+	return s, nil
+}
+
+// getTable returns the model of table, in the schema currently being dumped
+func (d *mySQL) getTable(table string) (*Table, error) {
+	s, err := d.getSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := s.Table(table)
+	if !ok {
+		return nil, fmt.Errorf("table %s not found in information_schema", table)
+	}
+
+	return t, nil
+}
+
+func (d *mySQL) loadSchema() (*Schema, error) {
+	s := &Schema{Name: d.schema, tables: make(map[string]*Table)}
+
+	if err := d.loadColumns(s); err != nil {
+		return nil, err
+	}
+
+	if err := d.loadKeys(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (d *mySQL) loadColumns(s *Schema) error {
+	rows, err := d.conn.QueryContext(
+		context.Background(),
+		"SELECT TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME, DATA_TYPE, COLUMN_TYPE, CHARACTER_MAXIMUM_LENGTH, "+
+			"IS_NULLABLE, COLUMN_KEY, EXTRA, CHARACTER_SET_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = "+
+			d.schemaExpression()+" ORDER BY TABLE_NAME, ORDINAL_POSITION",
+	)
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return a
+	}
+
+	defer d.closeSchemaRows(rows)
+
+	for rows.Next() {
+		var table, nullable, extra string
+		var length sql.NullInt64
+		var charset sql.NullString
+		c := &Column{}
+
+		// the schema selected by the connection is only known by its name from here
+		if dErr := rows.Scan(
+			&s.Name, &table, &c.Name, &c.DataType, &c.ColumnType, &length, &nullable, &c.Key, &extra, &charset,
+		); dErr != nil {
+			return dErr
+		}
+
+		c.DataType = strings.ToLower(c.DataType)
+		c.Length = length.Int64
+		c.Nullable = nullable == "YES"
+		c.Charset = charset.String
+
+		// DEFAULT_GENERATED flags default expressions, which are not generated columns
+		extra = strings.ToUpper(extra)
+		c.Generated = strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED") ||
+			strings.Contains(extra, "PERSISTENT GENERATED")
+		c.Invisible = strings.Contains(extra, "INVISIBLE")
+
+		t, ok := s.Table(table)
+		if !ok {
+			t = &Table{Name: table, UniqueKeys: make(map[string][]string), columns: make(map[string]*Column)}
+			s.Tables = append(s.Tables, t)
+			s.tables[strings.ToLower(table)] = t
+		}
+
+		t.Columns = append(t.Columns, c)
+		t.columns[strings.ToLower(c.Name)] = c
+	}
+
+	return rows.Err()
+}
+
+func (d *mySQL) loadKeys(s *Schema) error {
+	rows, err := d.conn.QueryContext(
+		context.Background(),
+		"SELECT CONSTRAINT_NAME, TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_SCHEMA, REFERENCED_TABLE_NAME, "+
+			"REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = "+d.schemaExpression()+
+			" ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION",
+	)
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return a
+	}
+
+	defer d.closeSchemaRows(rows)
+
+	for rows.Next() {
+		var name, table, column string
+		var refSchema, refTable, refColumn sql.NullString
+
+		if dErr := rows.Scan(&name, &table, &column, &refSchema, &refTable, &refColumn); dErr != nil {
+			return dErr
+		}
+
+		t, ok := s.Table(table)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "PRIMARY":
+			t.PrimaryKey = append(t.PrimaryKey, column)
+		case !refTable.Valid:
+			t.UniqueKeys[name] = append(t.UniqueKeys[name], column)
+		default:
+			// columns of a composite key come one per row, in order
+			last := len(t.ForeignKeys) - 1
+			if last < 0 || t.ForeignKeys[last].Name != name {
+				t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+					Name:             name,
+					ReferencedSchema: refSchema.String,
+					ReferencedTable:  refTable.String,
+				})
+				last++
+			}
+
+			t.ForeignKeys[last].Columns = append(t.ForeignKeys[last].Columns, column)
+			t.ForeignKeys[last].ReferencedColumns = append(t.ForeignKeys[last].ReferencedColumns, refColumn.String)
+		}
+	}
+
+	return rows.Err()
+}
+
+func (d *mySQL) closeSchemaRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		d.log.Error(
+			err.Error(),
+			zap.String("internal", "failed to close rows while loading schema"),
+		)
+	}
+}
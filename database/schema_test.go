@@ -0,0 +1,205 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectSchema expects the model of the schema to be loaded with tables, whose columns are
+// given as "name", "name type" or "name type extra", varchar being the default type. Keys are
+// rows of KEY_COLUMN_USAGE, see primaryKey and foreignKeyRow
+func expectSchema(mock sqlmock.Sqlmock, tables map[string][]string, keys ...[]driver.Value) {
+	columns := sqlmock.NewRows([]string{
+		"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "CHARACTER_MAXIMUM_LENGTH",
+		"IS_NULLABLE", "COLUMN_KEY", "EXTRA", "CHARACTER_SET_NAME",
+	})
+
+	for _, table := range sortedTables(tables) {
+		for _, spec := range tables[table] {
+			name, dataType, extra := parseColumnSpec(spec)
+			columns.AddRow("test", table, name, dataType, dataType, nil, "YES", "", extra, nil)
+		}
+	}
+
+	mock.ExpectQuery("FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ").WillReturnRows(columns)
+
+	rows := sqlmock.NewRows([]string{
+		"CONSTRAINT_NAME", "TABLE_NAME", "COLUMN_NAME", "REFERENCED_TABLE_SCHEMA", "REFERENCED_TABLE_NAME",
+		"REFERENCED_COLUMN_NAME",
+	})
+	for _, key := range keys {
+		rows.AddRow(key...)
+	}
+
+	mock.ExpectQuery("FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = ").WillReturnRows(rows)
+}
+
+// newTestSchema builds the model of a schema without querying it, out of the same column specs as expectSchema
+func newTestSchema(tables map[string][]string) *Schema {
+	s := &Schema{Name: "test", tables: make(map[string]*Table)}
+	for _, table := range sortedTables(tables) {
+		t := &Table{Name: table, UniqueKeys: make(map[string][]string), columns: make(map[string]*Column)}
+		for _, spec := range tables[table] {
+			name, dataType, extra := parseColumnSpec(spec)
+			c := &Column{
+				Name:       name,
+				DataType:   dataType,
+				ColumnType: dataType,
+				Nullable:   true,
+				Generated:  strings.Contains(extra, " GENERATED") && !strings.Contains(extra, "DEFAULT_GENERATED"),
+				Invisible:  strings.Contains(extra, "INVISIBLE"),
+			}
+			t.Columns = append(t.Columns, c)
+			t.columns[strings.ToLower(name)] = c
+		}
+
+		s.Tables = append(s.Tables, t)
+		s.tables[strings.ToLower(table)] = t
+	}
+
+	return s
+}
+
+func parseColumnSpec(spec string) (name, dataType, extra string) {
+	fields := strings.SplitN(spec, " ", 3)
+	dataType = "varchar"
+	if len(fields) > 1 {
+		dataType = fields[1]
+	}
+	if len(fields) > 2 {
+		extra = fields[2]
+	}
+
+	return fields[0], dataType, extra
+}
+
+func sortedTables(tables map[string][]string) []string {
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func primaryKey(table string, columns ...string) [][]driver.Value {
+	rows := make([][]driver.Value, 0, len(columns))
+	for _, column := range columns {
+		rows = append(rows, []driver.Value{"PRIMARY", table, column, nil, nil, nil})
+	}
+
+	return rows
+}
+
+func foreignKeyRow(name, table, column, refTable, refColumn string) []driver.Value {
+	return []driver.Value{name, table, column, "test", refTable, refColumn}
+}
+
+func TestMySQLLoadSchema(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.schema = "shop"
+
+	mock.ExpectQuery("SELECT TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME, DATA_TYPE, COLUMN_TYPE, CHARACTER_MAXIMUM_LENGTH, " +
+		"IS_NULLABLE, COLUMN_KEY, EXTRA, CHARACTER_SET_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = 'shop' " +
+		"ORDER BY TABLE_NAME, ORDINAL_POSITION").WillReturnRows(
+		sqlmock.NewRows([]string{
+			"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "CHARACTER_MAXIMUM_LENGTH",
+			"IS_NULLABLE", "COLUMN_KEY", "EXTRA", "CHARACTER_SET_NAME",
+		}).
+			AddRow("shop", "orders", "tenant_id", "int", "int unsigned", nil, "NO", "PRI", "", nil).
+			AddRow("shop", "orders", "id", "int", "int", nil, "NO", "PRI", "auto_increment", nil).
+			AddRow("shop", "orders", "user_id", "int", "int", nil, "YES", "MUL", "", nil).
+			AddRow("shop", "orders", "total", "decimal", "decimal(10,2)", nil, "YES", "", "VIRTUAL GENERATED", nil).
+			AddRow("shop", "orders", "created_at", "timestamp", "timestamp", nil, "YES", "", "DEFAULT_GENERATED", nil).
+			AddRow("shop", "users", "id", "int", "int", nil, "NO", "PRI", "", nil).
+			AddRow("shop", "users", "email", "varchar", "varchar(255)", 255, "NO", "UNI", "", "utf8mb4").
+			AddRow("shop", "users", "token", "VARBINARY", "varbinary(16)", nil, "YES", "", "INVISIBLE", nil),
+	)
+	mock.ExpectQuery("SELECT CONSTRAINT_NAME, TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_SCHEMA, REFERENCED_TABLE_NAME, " +
+		"REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = 'shop' " +
+		"ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION").WillReturnRows(
+		sqlmock.NewRows([]string{
+			"CONSTRAINT_NAME", "TABLE_NAME", "COLUMN_NAME", "REFERENCED_TABLE_SCHEMA", "REFERENCED_TABLE_NAME",
+			"REFERENCED_COLUMN_NAME",
+		}).
+			AddRow("PRIMARY", "orders", "tenant_id", nil, nil, nil).
+			AddRow("PRIMARY", "orders", "id", nil, nil, nil).
+			AddRow("fk_orders_user", "orders", "tenant_id", "shop", "users", "tenant_id").
+			AddRow("fk_orders_user", "orders", "user_id", "shop", "users", "id").
+			AddRow("PRIMARY", "users", "id", nil, nil, nil).
+			AddRow("uq_email", "users", "email", nil, nil, nil),
+	)
+
+	s, err := dumper.getSchema()
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// the model is only loaded once
+	again, err := dumper.getSchema()
+	assert.Nil(t, err)
+	assert.Same(t, s, again)
+
+	assert.Equal(t, "shop", s.Name)
+	assert.Len(t, s.Tables, 2)
+
+	orders, ok := s.Table("Orders")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"tenant_id", "id"}, orders.PrimaryKey)
+	assert.Equal(
+		t,
+		[]ForeignKey{{
+			Name:              "fk_orders_user",
+			Columns:           []string{"tenant_id", "user_id"},
+			ReferencedSchema:  "shop",
+			ReferencedTable:   "users",
+			ReferencedColumns: []string{"tenant_id", "id"},
+		}},
+		orders.ForeignKeys,
+	)
+
+	total, _ := orders.Column("total")
+	assert.True(t, total.Generated)
+	createdAt, _ := orders.Column("created_at")
+	assert.False(t, createdAt.Generated)
+
+	users, _ := s.Table("users")
+	assert.Equal(t, map[string][]string{"uq_email": {"email"}}, users.UniqueKeys)
+
+	email, _ := users.Column("EMAIL")
+	assert.Equal(
+		t,
+		&Column{Name: "email", DataType: "varchar", ColumnType: "varchar(255)", Length: 255, Key: "UNI", Charset: "utf8mb4"},
+		email,
+	)
+
+	token, _ := users.Column("token")
+	assert.True(t, token.Invisible)
+	assert.True(t, token.IsBinary())
+	assert.False(t, email.IsBinary())
+
+	_, err = dumper.getTable("missing")
+	assert.EqualError(t, err, "table missing not found in information_schema")
+}
+
+func TestMySQLLoadSchemaHandlingError(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+
+	mock.ExpectQuery("FROM information_schema.COLUMNS").WillReturnError(errors.New("denied"))
+	_, err := dumper.getSchema()
+	assert.EqualError(t, err, "denied")
+
+	// a failed load is not cached
+	expectSchema(mock, map[string][]string{"users": {"id int"}})
+	_, err = dumper.getTable("users")
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
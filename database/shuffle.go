@@ -0,0 +1,372 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ShuffleMemory is the amount of values, in bytes, buffered in memory for each shuffled column
+// before they are spilled to a temporary file
+const ShuffleMemory = 32 << 20
+
+var shuffleRuleRegExp = regexp.MustCompile(`^shuffle(?:\(\s*(?:by\s*=\s*)?([^)]*?)\s*\))?$`)
+
+// shuffledColumn hands out the values of a column after they were permuted across the rows
+// of its table, in the order the rows are read, along with their primary key
+type shuffledColumn struct {
+	sorters []*externalSorter
+	values  recordIterator
+	pending record
+}
+
+// isShuffle tells whether rule permutes the values of its column across the rows of its table
+func isShuffle(rule string) bool {
+	return shuffleRuleRegExp.MatchString(rule)
+}
+
+// hasShuffles tells whether any column of table is shuffled
+func (d *mySQL) hasShuffles(table string) bool {
+	for _, rule := range d.selectFor(table) {
+		if isShuffle(rule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shuffleGroup returns the column a shuffle rule permutes values within, nothing when it
+// permutes them across the whole table, as in shuffle or shuffle(by=department)
+func shuffleGroup(rule string) string {
+	match := shuffleRuleRegExp.FindStringSubmatch(rule)
+	if match == nil {
+		return ""
+	}
+
+	return strings.Trim(match[1], "`'\"")
+}
+
+// getShufflesFor permutes the values of the shuffled columns of table, which are nil for the other columns.
+// Rows are read ordered by key, which the rows being dumped must be read by as well
+func (d *mySQL) getShufflesFor(table string, columns, key []string) ([]*shuffledColumn, error) {
+	var shuffled []int
+	for i, column := range columns {
+		if isShuffle(d.selectFor(table)[strings.ToLower(strings.Trim(column, "`"))]) {
+			shuffled = append(shuffled, i)
+		}
+	}
+
+	if len(shuffled) == 0 {
+		return nil, nil
+	}
+
+	if len(key) == 0 {
+		return nil, fmt.Errorf("table %s has shuffled columns, which requires a primary key", table)
+	}
+
+	values := make([]*externalSorter, len(shuffled))
+	positions := make([]*externalSorter, len(shuffled))
+	for i := range shuffled {
+		// values are ordered at random within their group, and rows by their position within theirs
+		values[i] = newExternalSorter(d.shuffleMemory, func(a, b record) bool {
+			return compareFields(a, b, 0, 1) < 0
+		})
+		positions[i] = newExternalSorter(d.shuffleMemory, func(a, b record) bool {
+			return compareFields(a, b, 0, 1) < 0
+		})
+	}
+
+	closeSorters := func() {
+		for i := range shuffled {
+			values[i].close()
+			positions[i].close()
+		}
+	}
+
+	if err := d.readShuffledValues(table, columns, key, shuffled, values, positions); err != nil {
+		closeSorters()
+		return nil, err
+	}
+
+	shuffles := make([]*shuffledColumn, len(columns))
+	for i, column := range shuffled {
+		s, err := pairShuffledValues(values[i], positions[i])
+		if err != nil {
+			closeSorters()
+			for _, done := range shuffles {
+				if done != nil {
+					done.close()
+				}
+			}
+
+			return nil, err
+		}
+
+		shuffles[column] = s
+	}
+
+	return shuffles, nil
+}
+
+// shuffleReader adds the values of the shuffled columns of a table to their sorters, as they are read
+type shuffleReader struct {
+	table     string
+	values    []*externalSorter
+	positions []*externalSorter
+	random    []*rand.Rand
+	// position is the position of the next row read, across every chunk
+	position uint64
+}
+
+// readShuffledValues reads the values of the shuffled columns, for each one adding (group, random, value)
+// to its values and (group, position, key) to its positions. Rows are read ordered by key, in chunks of
+// chunkSize rows when it is set, as the rows being dumped are
+func (d *mySQL) readShuffledValues(
+	table string,
+	columns, key []string,
+	shuffled []int,
+	values, positions []*externalSorter,
+) error {
+	selected := make([]string, 0, 2*len(shuffled))
+	random := make([]*rand.Rand, 0, len(shuffled))
+	for _, i := range shuffled {
+		column := strings.Trim(columns[i], "`")
+
+		group := "NULL"
+		if by := shuffleGroup(d.selectFor(table)[strings.ToLower(column)]); by != "" {
+			group = d.qualify(table) + "." + quoteIdentifier(by)
+		}
+
+		selected = append(selected, d.qualify(table)+"."+quoteIdentifier(column), group)
+		random = append(random, d.shuffleRandom(table, column))
+	}
+
+	r := &shuffleReader{table: table, values: values, positions: positions, random: random}
+
+	var after [][]byte
+	for {
+		query, args, err := d.getKeyedSelectQueryFor(table, selected, key, after)
+		if err != nil {
+			return err
+		}
+
+		read, lastKey, err := d.readShuffledChunk(r, query, args, len(selected), len(key))
+		if err != nil {
+			return err
+		}
+
+		if d.chunkSize == 0 || read < d.chunkSize {
+			return nil
+		}
+
+		after = lastKey
+	}
+}
+
+// readShuffledChunk reads the rows of a single query, returning how many there were and the key of the last one
+func (d *mySQL) readShuffledChunk(r *shuffleReader, query string, args []interface{}, selected, keys int) (
+	read int,
+	lastKey [][]byte,
+	err error,
+) {
+	rows, err := d.conn.QueryContext(context.Background(), query, args...)
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return 0, nil, a
+	}
+
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("table", r.table),
+				zap.String("context", "shuffling data, closing rows failed"),
+			)
+		}
+	}(rows)
+
+	raw := make([]*sql.RawBytes, selected+keys)
+	scanArgs := make([]interface{}, len(raw))
+	for i := range raw {
+		scanArgs[i] = &raw[i]
+	}
+
+	position := make([]byte, 8)
+	for ; rows.Next(); read++ {
+		if err = rows.Scan(scanArgs...); err != nil {
+			return 0, nil, err
+		}
+
+		binary.BigEndian.PutUint64(position, r.position)
+		r.position++
+
+		rowKey := encodeKey(raw[selected:])
+		lastKey = copyKey(raw[selected:])
+
+		for i := range r.values {
+			group := groupOf(raw[2*i+1])
+
+			order := make([]byte, 8)
+			binary.BigEndian.PutUint64(order, r.random[i].Uint64())
+
+			if err = r.values[i].add(record{group, order, rawField(raw[2*i])}); err != nil {
+				return 0, nil, err
+			}
+
+			if err = r.positions[i].add(record{group, position, rowKey}); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+
+	return read, lastKey, rows.Err()
+}
+
+// pairShuffledValues hands every row of a group a value of the same group, values being in random
+// order within their group and rows in their original order within theirs
+func pairShuffledValues(values, positions *externalSorter) (*shuffledColumn, error) {
+	byPosition := newExternalSorter(values.limit, func(a, b record) bool {
+		return compareFields(a, b, 0) < 0
+	})
+
+	s := &shuffledColumn{sorters: []*externalSorter{values, positions, byPosition}}
+
+	shuffledValues, err := values.sorted()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := positions.sorted()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		row, rErr := rows.next()
+		if errors.Is(rErr, io.EOF) {
+			break
+		}
+
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		// every group has as many values as rows, so both are read at the same pace
+		value, vErr := shuffledValues.next()
+		if vErr != nil {
+			return nil, vErr
+		}
+
+		if dErr := byPosition.add(record{row[1], row[2], value[2]}); dErr != nil {
+			return nil, dErr
+		}
+	}
+
+	if s.values, err = byPosition.sorted(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// valueFor returns the shuffled value of the row with key. Rows are expected in the order they
+// were shuffled in, but some may be missing, as when the dump of a table is resumed
+func (s *shuffledColumn) valueFor(key []*sql.RawBytes) (*sql.RawBytes, error) {
+	want := encodeKey(key)
+
+	for {
+		if s.pending == nil {
+			r, err := s.values.next()
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("row not found among the shuffled ones, the table changed while it was dumped")
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			s.pending = r
+		}
+
+		r := s.pending
+		s.pending = nil
+
+		if bytes.Equal(r[1], want) {
+			if r[2] == nil {
+				return nil, nil
+			}
+
+			value := sql.RawBytes(r[2])
+
+			return &value, nil
+		}
+	}
+}
+
+// close removes every file spilled to disk while shuffling
+func (s *shuffledColumn) close() {
+	for _, sorter := range s.sorters {
+		sorter.close()
+	}
+}
+
+// shuffleRandom returns the source the order of the values of column is drawn from,
+// seeded from the seed, table and column of seeded dumps
+func (d *mySQL) shuffleRandom(table, column string) *rand.Rand {
+	if d.seed == nil {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	h := sha256.New()
+	_ = binary.Write(h, binary.BigEndian, *d.seed)
+	h.Write([]byte(d.filterKey(table)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.ToLower(column)))
+	sum := h.Sum(nil)
+
+	return rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+}
+
+// groupOf tells NULL groups apart from empty ones, which compare the same as raw bytes
+func groupOf(value *sql.RawBytes) []byte {
+	if value == nil {
+		return []byte{0}
+	}
+
+	return append([]byte{1}, *value...)
+}
+
+func rawField(value *sql.RawBytes) []byte {
+	if value == nil {
+		return nil
+	}
+
+	return append([]byte{}, *value...)
+}
+
+// encodeKey writes the values of a key one after the other, each preceded by its length
+func encodeKey(key []*sql.RawBytes) []byte {
+	var b []byte
+	for _, value := range key {
+		if value == nil {
+			b = binary.AppendUvarint(b, 0)
+			continue
+		}
+
+		b = binary.AppendUvarint(b, uint64(len(*value))+1)
+		b = append(b, *value...)
+	}
+
+	return b
+}
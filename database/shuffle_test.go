@@ -0,0 +1,250 @@
+package database
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var insertedRowRegExp = regexp.MustCompile(`\( (\d+), '(\w+)', (\d+|NULL) \)`)
+
+type shuffleRow struct {
+	id     int
+	dept   string
+	salary driver.Value
+}
+
+var shuffleRows = []shuffleRow{
+	{1, "sales", 1000},
+	{2, "sales", 1100},
+	{3, "it", 2000},
+	{4, "sales", 1200},
+	{5, "it", 2100},
+	{6, "it", nil},
+}
+
+// dumpShuffled dumps the salaries of shuffleRows shuffled by rule, returning the dumped salaries by id
+func dumpShuffled(t *testing.T, rule string, seed int64, memory int) map[string]string {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.selectMap = map[string]map[string]string{"employees": {"salary": rule}}
+	dumper.seed = &seed
+	dumper.shuffleMemory = memory
+
+	expectSchema(
+		mock,
+		map[string][]string{"employees": {"id int", "dept", "salary int"}},
+		primaryKey("employees", "id")...,
+	)
+
+	group := "NULL"
+	if strings.Contains(rule, "dept") {
+		group = "`employees`.`dept`"
+	}
+
+	shuffled := sqlmock.NewRows([]string{"salary", "dept", "id"})
+	dumped := sqlmock.NewRowsWithColumnDefinition(
+		sqlmock.NewColumn("id").OfType("INT", 0),
+		sqlmock.NewColumn("dept").OfType("VARCHAR", ""),
+		sqlmock.NewColumn("salary").OfType("INT", 0),
+		sqlmock.NewColumn("id").OfType("INT", 0),
+	)
+	for _, row := range shuffleRows {
+		shuffled.AddRow(row.salary, row.dept, row.id)
+		dumped.AddRow(row.id, row.dept, row.salary, row.id)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT `employees`.`salary`, " + group + ", `employees`.`id` FROM `employees` ORDER BY `employees`.`id`",
+	)).WillReturnRows(shuffled)
+
+	// the original salaries are selected, and replaced by the shuffled ones
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT `id`, `dept`, `salary`, `employees`.`id` FROM `employees` ORDER BY `employees`.`id`",
+	)).WillReturnRows(dumped)
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "employees"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	salaries := make(map[string]string)
+	for _, match := range insertedRowRegExp.FindAllStringSubmatch(buffer.String(), -1) {
+		salaries[match[1]] = match[3]
+	}
+
+	assert.Len(t, salaries, len(shuffleRows), buffer.String())
+
+	return salaries
+}
+
+func salariesOf(salaries map[string]string, ids ...string) []string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, salaries[id])
+	}
+
+	sort.Strings(values)
+
+	return values
+}
+
+func TestMySQLDumpTableDataWithShuffle(t *testing.T) {
+	for _, memory := range []int{ShuffleMemory, 1} {
+		salaries := dumpShuffled(t, "shuffle", 42, memory)
+
+		// every salary is kept, on a row of its own
+		assert.Equal(
+			t,
+			[]string{"1000", "1100", "1200", "2000", "2100", "NULL"},
+			salariesOf(salaries, "1", "2", "3", "4", "5", "6"),
+		)
+
+		// seeded dumps shuffle the same way on every run, whatever they spill to disk
+		assert.Equal(t, salaries, dumpShuffled(t, "shuffle", 42, ShuffleMemory))
+	}
+}
+
+func TestMySQLDumpTableDataWithGroupedShuffle(t *testing.T) {
+	for _, rule := range []string{"shuffle(by=dept)", "shuffle(`dept`)"} {
+		salaries := dumpShuffled(t, rule, 7, 1)
+
+		// salaries only move between employees of the same department
+		assert.Equal(t, []string{"1000", "1100", "1200"}, salariesOf(salaries, "1", "2", "4"))
+		assert.Equal(t, []string{"2000", "2100", "NULL"}, salariesOf(salaries, "3", "5", "6"))
+	}
+}
+
+func TestMySQLDumpTableDataWithShuffleAndWhere(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.selectMap = map[string]map[string]string{"employees": {"salary": "shuffle"}}
+	dumper.whereMap = map[string]string{"employees": "id > 1"}
+
+	expectSchema(
+		mock,
+		map[string][]string{"employees": {"id int", "salary int"}},
+		primaryKey("employees", "id")...,
+	)
+
+	// only the dumped rows are shuffled
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT `employees`.`salary`, NULL, `employees`.`id` FROM `employees` WHERE (id > 1) ORDER BY `employees`.`id`",
+	)).WillReturnRows(sqlmock.NewRows([]string{"salary", "group", "id"}).AddRow(1000, nil, 2))
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT `id`, `salary`, `employees`.`id` FROM `employees` WHERE (id > 1) ORDER BY `employees`.`id`",
+	)).WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT", 0),
+			sqlmock.NewColumn("salary").OfType("INT", 0),
+			sqlmock.NewColumn("id").OfType("INT", 0),
+		).AddRow(2, 1000, 2),
+	)
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "employees"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "INSERT INTO `employees` (`id`, `salary`) VALUES\n( 2, 1000 );\n", buffer.String())
+}
+
+func TestMySQLDumpTableDataWithShuffleInChunks(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.selectMap = map[string]map[string]string{"employees": {"salary": "shuffle"}}
+	dumper.chunkSize = 2
+
+	expectSchema(
+		mock,
+		map[string][]string{"employees": {"id int", "salary int"}},
+		primaryKey("employees", "id")...,
+	)
+
+	// the values are read in chunks too, each one after the last key of the previous one
+	shuffled := "SELECT `employees`.`salary`, NULL, `employees`.`id` FROM `employees` "
+	mock.ExpectQuery(regexp.QuoteMeta(shuffled + "ORDER BY `employees`.`id` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"salary", "group", "id"}).AddRow(1000, nil, 1).AddRow(1100, nil, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(shuffled + "WHERE (`employees`.`id`) > (?) ORDER BY `employees`.`id` LIMIT 2")).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"salary", "group", "id"}).AddRow(1200, nil, 3))
+
+	dumped := "SELECT `id`, `salary`, `employees`.`id` FROM `employees` "
+	columns := []*sqlmock.Column{
+		sqlmock.NewColumn("id").OfType("INT", 0),
+		sqlmock.NewColumn("salary").OfType("INT", 0),
+		sqlmock.NewColumn("id").OfType("INT", 0),
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(dumped + "ORDER BY `employees`.`id` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(columns...).AddRow(1, 1000, 1).AddRow(2, 1100, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(dumped + "WHERE (`employees`.`id`) > (?) ORDER BY `employees`.`id` LIMIT 2")).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(columns...).AddRow(3, 1200, 3))
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "employees"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	salaries := regexp.MustCompile(`\( \d, (\d+) \)`).FindAllStringSubmatch(buffer.String(), -1)
+	got := make([]string, 0, len(salaries))
+	for _, salary := range salaries {
+		got = append(got, salary[1])
+	}
+
+	sort.Strings(got)
+	assert.Equal(t, []string{"1000", "1100", "1200"}, got)
+}
+
+func TestMySQLDumpTableDataWithShuffleWithoutPrimaryKey(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.selectMap = map[string]map[string]string{"logs": {"message": "shuffle"}}
+
+	expectSchema(mock, map[string][]string{"logs": {"message"}})
+
+	err := dumper.dumpTableData(new(bytes.Buffer), "logs")
+	assert.EqualError(t, err, "table logs has shuffled columns, which requires a primary key")
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDumpTableDataWithShuffleOfChangedTable(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.selectMap = map[string]map[string]string{"employees": {"salary": "shuffle"}}
+
+	expectSchema(
+		mock,
+		map[string][]string{"employees": {"id int", "salary int"}},
+		primaryKey("employees", "id")...,
+	)
+
+	mock.ExpectQuery("SELECT `employees`.`salary`, NULL, `employees`.`id` FROM `employees` ORDER BY").
+		WillReturnRows(sqlmock.NewRows([]string{"salary", "group", "id"}).AddRow(1000, nil, 1))
+
+	// a row inserted after the values were shuffled
+	mock.ExpectQuery("SELECT `id`, `salary`, `employees`.`id` FROM `employees` ORDER BY").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "salary", "id"}).AddRow(1, 1000, 1).AddRow(2, 2000, 2),
+	)
+
+	err := dumper.dumpTableData(new(bytes.Buffer), "employees")
+	assert.EqualError(
+		t,
+		err,
+		"shuffling column `salary` of table employees: row not found among the shuffled ones, the table changed while it was dumped",
+	)
+}
+
+func Test_shuffleGroup(t *testing.T) {
+	assert.True(t, isShuffle("shuffle"))
+	assert.True(t, isShuffle("shuffle( by = dept )"))
+	assert.False(t, isShuffle("shuffle_name()"))
+	assert.False(t, isShuffle("go:shuffle"))
+
+	assert.Equal(t, "", shuffleGroup("shuffle"))
+	assert.Equal(t, "dept", shuffleGroup("shuffle( by = dept )"))
+	assert.Equal(t, "dept", shuffleGroup("shuffle(`dept`)"))
+}
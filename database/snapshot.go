@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// snapshot holds every connection the dump reads from, each one inside a transaction
+// started WITH CONSISTENT SNAPSHOT, so they all see the data at the same point in time
+type snapshot struct {
+	// conns lists every connection, the first one is the main connection of the dump
+	conns []*sql.Conn
+	// workers hands the remaining connections to parallel workers, which give them back once done
+	workers chan *sql.Conn
+}
+
+// startSnapshot opens the snapshot transactions of a dump that runs in a single transaction,
+// one for the dump and one for each parallel worker. Every read goes through them from then on
+func (d *mySQL) startSnapshot() error {
+	if d.masterData != 0 && !d.singleTransaction {
+		return errors.New("master data requires a single transaction")
+	}
+
+	// the rest of a resumed dump comes from a new snapshot, at another position than the one already written
+	if d.masterData != 0 && d.resume {
+		return errors.New("a dump with master data cannot be resumed, its position would not match the rows after it")
+	}
+
+	if !d.singleTransaction || d.snapshot != nil {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	workers := 0
+	if d.parallel > 1 {
+		workers = d.parallel
+	}
+
+	s := &snapshot{workers: make(chan *sql.Conn, workers)}
+
+	main, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.conns = append(s.conns, main)
+
+	// a single connection is consistent on its own, several need to be started
+	// while nothing can be written, so they all start at the same point. The same
+	// goes for the binary log position, which must match the snapshot
+	locked := false
+	if workers > 0 || d.masterData != 0 {
+		_, err = main.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK")
+
+		switch {
+		case err == nil:
+			locked = true
+		case d.masterData != 0:
+			d.abortSnapshot(s)
+			return err
+		default:
+			d.log.Warn(
+				"could not lock tables, parallel connections may not share the exact same snapshot",
+				zap.Error(err),
+			)
+		}
+	}
+
+	if err = startSnapshotTransaction(ctx, main); err != nil {
+		d.abortSnapshot(s)
+		return err
+	}
+
+	if d.masterData != 0 {
+		if d.binlogPosition, err = d.getBinlogPosition(ctx, main); err != nil {
+			d.abortSnapshot(s)
+			return err
+		}
+
+		d.log.Info(
+			"snapshot taken",
+			zap.String("binlog_file", d.binlogPosition.File),
+			zap.Uint64("binlog_position", d.binlogPosition.Position),
+			zap.String("gtid_executed", d.binlogPosition.GTIDExecuted),
+		)
+	}
+
+	for i := 0; i < workers; i++ {
+		conn, cErr := d.db.Conn(ctx)
+		if cErr != nil {
+			d.abortSnapshot(s)
+			return cErr
+		}
+
+		s.conns = append(s.conns, conn)
+
+		if cErr = startSnapshotTransaction(ctx, conn); cErr != nil {
+			d.abortSnapshot(s)
+			return cErr
+		}
+
+		s.workers <- conn
+	}
+
+	if locked {
+		// releasing a global read lock does not end the transactions
+		if _, err = main.ExecContext(ctx, "UNLOCK TABLES"); err != nil {
+			d.abortSnapshot(s)
+			return err
+		}
+	}
+
+	d.snapshot = s
+	d.conn = main
+
+	return nil
+}
+
+func startSnapshotTransaction(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+		return err
+	}
+
+	_, err := conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT")
+
+	return err
+}
+
+// abortSnapshot discards every connection of a snapshot that failed to start, instead of
+// handing them back to the pool, which releases the lock and rolls back their transactions
+func (d *mySQL) abortSnapshot(s *snapshot) {
+	for _, conn := range s.conns {
+		_ = conn.Raw(func(interface{}) error {
+			return driver.ErrBadConn
+		})
+
+		_ = conn.Close()
+	}
+}
+
+// workerConn returns the connection a parallel worker runs on, taken from the snapshot when
+// there is one, along with the func that must be called once the worker is done with it
+func (d *mySQL) workerConn(ctx context.Context) (*sql.Conn, func(), error) {
+	if d.snapshot != nil && cap(d.snapshot.workers) > 0 {
+		conn := <-d.snapshot.workers
+
+		return conn, func() {
+			d.snapshot.workers <- conn
+		}, nil
+	}
+
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, func() {
+		closeConn(d.log, conn)
+	}, nil
+}
+
+func closeConn(log *zap.Logger, conn *sql.Conn) {
+	if err := conn.Close(); err != nil {
+		log.Warn(err.Error(), zap.String("context", "closing connection"))
+	}
+}
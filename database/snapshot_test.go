@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func expectSnapshotTransaction(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("START TRANSACTION WITH CONSISTENT SNAPSHOT").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMySQLStartSnapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		parallel int
+		lockErr  error
+	}{
+		{name: "single connection needs no lock", parallel: 1},
+		{name: "parallel connections start under a global read lock", parallel: 2},
+		{name: "parallel connections without privileges to lock", parallel: 2, lockErr: errors.New("access denied")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := getDB(t)
+			dumper := getInternalMySQLInstance(db, nil)
+			dumper.log = zap.NewNop()
+			dumper.singleTransaction = true
+			dumper.parallel = tt.parallel
+
+			if tt.parallel > 1 {
+				lock := mock.ExpectExec("FLUSH TABLES WITH READ LOCK")
+				if tt.lockErr != nil {
+					lock.WillReturnError(tt.lockErr)
+				} else {
+					lock.WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+			}
+
+			// the main connection, plus one for each worker
+			expectSnapshotTransaction(mock)
+			for i := 0; i < tt.parallel && tt.parallel > 1; i++ {
+				expectSnapshotTransaction(mock)
+			}
+
+			if tt.parallel > 1 && tt.lockErr == nil {
+				mock.ExpectExec("UNLOCK TABLES").WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			assert.Nil(t, dumper.startSnapshot())
+			assert.NotNil(t, dumper.snapshot)
+			assert.Equal(t, dumper.snapshot.conns[0], dumper.conn)
+
+			// every worker borrows a connection of the snapshot and gives it back
+			conn, release, err := dumper.workerConn(context.Background())
+			assert.Nil(t, err)
+			if tt.parallel > 1 {
+				assert.Contains(t, dumper.snapshot.conns[1:], conn)
+			} else {
+				assert.NotContains(t, dumper.snapshot.conns, conn)
+			}
+
+			release()
+			assert.Len(t, dumper.snapshot.workers, cap(dumper.snapshot.workers))
+
+			// reads go through the snapshot connection
+			mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `users`").WillReturnRows(
+				sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(3),
+			)
+			count, err := dumper.rowCount("users")
+			assert.Nil(t, err)
+			assert.Equal(t, uint64(3), count)
+
+			for range dumper.snapshot.conns {
+				mock.ExpectExec("COMMIT").WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			dumper.commitTransaction()
+			assert.Nil(t, dumper.snapshot)
+			assert.Equal(t, connection(db), dumper.conn)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMySQLStartSnapshotOnlyWithSingleTransaction(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.parallel = 4
+
+	assert.Nil(t, dumper.startSnapshot())
+	assert.Nil(t, dumper.snapshot)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLStartSnapshotRecordsMasterData(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.log = zap.NewNop()
+	dumper.singleTransaction = true
+	dumper.masterData = MasterDataComment
+
+	mock.ExpectExec("FLUSH TABLES WITH READ LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSnapshotTransaction(mock)
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnError(errors.New("syntax error"))
+	mock.ExpectQuery("SHOW BINARY LOG STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).
+			AddRow("binlog.000003", "157", "", "", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,\n4e11fa47-71ca-11e1-9e33-c80aa9429562:1-2"),
+	)
+	mock.ExpectExec("UNLOCK TABLES").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Nil(t, dumper.startSnapshot())
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		&BinlogPosition{
+			File:         "binlog.000003",
+			Position:     157,
+			GTIDExecuted: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4e11fa47-71ca-11e1-9e33-c80aa9429562:1-2",
+		},
+		dumper.binlogPosition,
+	)
+	assert.Equal(
+		t,
+		"\n--\n-- Position to start replication or point-in-time recovery from\n--\n\n"+
+			"-- CHANGE MASTER TO MASTER_LOG_FILE='binlog.000003', MASTER_LOG_POS=157;\n"+
+			"-- SET @@GLOBAL.GTID_PURGED='3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4e11fa47-71ca-11e1-9e33-c80aa9429562:1-2';\n\n",
+		dumper.getMasterDataStatement(),
+	)
+
+	dumper.masterData = MasterDataStatement
+	assert.Contains(t, dumper.getMasterDataStatement(), "\nCHANGE MASTER TO MASTER_LOG_FILE='binlog.000003', MASTER_LOG_POS=157;\n")
+}
+
+func TestMySQLStartSnapshotMasterDataErrors(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.masterData = MasterDataStatement
+	assert.EqualError(t, dumper.startSnapshot(), "master data requires a single transaction")
+
+	dumper.singleTransaction = true
+	dumper.resume = true
+	assert.EqualError(
+		t,
+		dumper.startSnapshot(),
+		"a dump with master data cannot be resumed, its position would not match the rows after it",
+	)
+
+	// the position would not match the snapshot without the lock
+	dumper.resume = false
+	mock.ExpectExec("FLUSH TABLES WITH READ LOCK").WillReturnError(errors.New("access denied"))
+	assert.EqualError(t, dumper.startSnapshot(), "access denied")
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// a failed snapshot discards its connection, so it needs a new database
+	db, mock = getDB(t)
+	dumper = getInternalMySQLInstance(db, nil)
+	dumper.masterData = MasterDataStatement
+	dumper.singleTransaction = true
+
+	mock.ExpectExec("FLUSH TABLES WITH READ LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSnapshotTransaction(mock)
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}),
+	)
+	assert.EqualError(t, dumper.startSnapshot(), "binary logging is not enabled, there is no position to record")
+	assert.Nil(t, dumper.snapshot)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+// record is a row of fields spilled to disk while sorting, nil fields being NULL
+type record [][]byte
+
+// recordIterator returns records one at a time, and io.EOF once there are no more
+type recordIterator interface {
+	next() (record, error)
+}
+
+const (
+	// recordOverhead and fieldOverhead are the memory taken by a buffered record besides the bytes of
+	// its fields, the slice headers of the record and of each field, without which many short values
+	// would take far more memory than the limit before being spilled
+	recordOverhead = 24
+	fieldOverhead  = 24
+)
+
+// externalSorter sorts any number of records within a bounded amount of memory: records are
+// buffered until they take limit bytes, then sorted and spilled to a temporary file as a run.
+// Runs are merged back together when the records are read
+type externalSorter struct {
+	less    func(a, b record) bool
+	limit   int
+	size    int
+	records []record
+	runs    []*os.File
+}
+
+func newExternalSorter(limit int, less func(a, b record) bool) *externalSorter {
+	return &externalSorter{less: less, limit: limit}
+}
+
+// add copies r into the sorter, spilling the buffered records when they exceed the limit
+func (s *externalSorter) add(r record) error {
+	c := make(record, len(r))
+	s.size += recordOverhead + fieldOverhead*len(r)
+	for i, field := range r {
+		if field != nil {
+			c[i] = append([]byte{}, field...)
+			s.size += len(field)
+		}
+	}
+
+	s.records = append(s.records, c)
+	if s.size < s.limit {
+		return nil
+	}
+
+	return s.spill()
+}
+
+func (s *externalSorter) spill() error {
+	sort.SliceStable(s.records, func(i, j int) bool {
+		return s.less(s.records[i], s.records[j])
+	})
+
+	f, err := os.CreateTemp("", "go-mad-sort-*")
+	if err != nil {
+		return err
+	}
+
+	s.runs = append(s.runs, f)
+
+	w := bufio.NewWriter(f)
+	for _, r := range s.records {
+		if err = writeRecord(w, r); err != nil {
+			return err
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	s.records = s.records[:0]
+	s.size = 0
+
+	return nil
+}
+
+// sorted returns the records added so far, in order. Nothing can be added afterwards
+func (s *externalSorter) sorted() (recordIterator, error) {
+	if len(s.runs) == 0 {
+		sort.SliceStable(s.records, func(i, j int) bool {
+			return s.less(s.records[i], s.records[j])
+		})
+
+		return &sliceIterator{records: s.records}, nil
+	}
+
+	if len(s.records) > 0 {
+		if err := s.spill(); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &mergeIterator{less: s.less, sources: make([]*bufio.Reader, len(s.runs))}
+	for i, f := range s.runs {
+		m.sources[i] = bufio.NewReader(f)
+
+		r, err := readRecord(m.sources[i])
+		if errors.Is(err, io.EOF) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		m.heads = append(m.heads, mergeHead{record: r, run: i})
+	}
+
+	heap.Init(m)
+
+	return m, nil
+}
+
+// close removes the runs spilled to disk
+func (s *externalSorter) close() {
+	for _, f := range s.runs {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+
+	s.runs = nil
+	s.records = nil
+}
+
+type sliceIterator struct {
+	records []record
+}
+
+func (it *sliceIterator) next() (record, error) {
+	if len(it.records) == 0 {
+		return nil, io.EOF
+	}
+
+	r := it.records[0]
+	it.records = it.records[1:]
+
+	return r, nil
+}
+
+type mergeHead struct {
+	record record
+	run    int
+}
+
+// mergeIterator merges sorted runs, always returning the smallest of their next records
+type mergeIterator struct {
+	less    func(a, b record) bool
+	heads   []mergeHead
+	sources []*bufio.Reader
+}
+
+func (m *mergeIterator) Len() int           { return len(m.heads) }
+func (m *mergeIterator) Less(i, j int) bool { return m.less(m.heads[i].record, m.heads[j].record) }
+func (m *mergeIterator) Swap(i, j int)      { m.heads[i], m.heads[j] = m.heads[j], m.heads[i] }
+func (m *mergeIterator) Push(x interface{}) { m.heads = append(m.heads, x.(mergeHead)) }
+
+func (m *mergeIterator) Pop() interface{} {
+	last := m.heads[len(m.heads)-1]
+	m.heads = m.heads[:len(m.heads)-1]
+
+	return last
+}
+
+func (m *mergeIterator) next() (record, error) {
+	if len(m.heads) == 0 {
+		return nil, io.EOF
+	}
+
+	head := m.heads[0]
+
+	r, err := readRecord(m.sources[head.run])
+	switch {
+	case errors.Is(err, io.EOF):
+		heap.Pop(m)
+	case err != nil:
+		return nil, err
+	default:
+		m.heads[0].record = r
+		heap.Fix(m, 0)
+	}
+
+	return head.record, nil
+}
+
+// writeRecord writes the number of fields of r, then each field preceded by its length plus one, 0 being NULL
+func writeRecord(w *bufio.Writer, r record) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	if _, err := w.Write(buf[:binary.PutUvarint(buf, uint64(len(r)))]); err != nil {
+		return err
+	}
+
+	for _, field := range r {
+		n := uint64(0)
+		if field != nil {
+			n = uint64(len(field)) + 1
+		}
+
+		if _, err := w.Write(buf[:binary.PutUvarint(buf, n)]); err != nil {
+			return err
+		}
+
+		if _, err := w.Write(field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readRecord(src *bufio.Reader) (record, error) {
+	count, err := binary.ReadUvarint(src)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(record, count)
+	for i := range r {
+		n, dErr := binary.ReadUvarint(src)
+		if dErr != nil {
+			return nil, unexpectedEOF(dErr)
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		r[i] = make([]byte, n-1)
+		if _, dErr = io.ReadFull(src, r[i]); dErr != nil {
+			return nil, unexpectedEOF(dErr)
+		}
+	}
+
+	return r, nil
+}
+
+// unexpectedEOF tells a truncated record apart from the end of a run
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// compareFields compares a and b field by field, in the order of fields, NULL coming first
+func compareFields(a, b record, fields ...int) int {
+	for _, i := range fields {
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return -1
+		case b[i] == nil:
+			return 1
+		}
+
+		if c := bytes.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
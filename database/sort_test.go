@@ -0,0 +1,98 @@
+package database
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sortedRecords(t *testing.T, s *externalSorter) []record {
+	it, err := s.sorted()
+	assert.Nil(t, err)
+
+	var records []record
+	for {
+		r, err := it.next()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+
+		assert.Nil(t, err)
+		records = append(records, r)
+	}
+}
+
+func TestExternalSorter(t *testing.T) {
+	byFirst := func(a, b record) bool {
+		return compareFields(a, b, 0) < 0
+	}
+
+	for _, limit := range []int{1 << 20, 1, 7} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			s := newExternalSorter(limit, byFirst)
+			defer s.close()
+
+			for _, n := range []string{"5", "3", "9", "1", "7", "2", "8", "4", "6", "0"} {
+				assert.Nil(t, s.add(record{[]byte(n), []byte("v" + n)}))
+			}
+
+			// a NULL first field comes before any other, and a NULL second one is kept
+			assert.Nil(t, s.add(record{nil, nil}))
+
+			// the sorter keeps its own copy of what is added
+			shared := []byte("a")
+			assert.Nil(t, s.add(record{shared, []byte{}}))
+			shared[0] = 'z'
+
+			// every record reaches the limit on its own, even the empty one
+			if limit == 1 {
+				assert.Len(t, s.runs, 12)
+			}
+
+			got := sortedRecords(t, s)
+			assert.Equal(t, record{nil, nil}, got[0])
+			for i := 0; i < 10; i++ {
+				n := strconv.Itoa(i)
+				assert.Equal(t, record{[]byte(n), []byte("v" + n)}, got[i+1])
+			}
+			assert.Equal(t, record{[]byte("a"), []byte{}}, got[11])
+		})
+	}
+}
+
+func TestExternalSorterCountsRecordOverhead(t *testing.T) {
+	s := newExternalSorter(1000, func(a, b record) bool { return false })
+	defer s.close()
+
+	// 200 bytes of values, but ten times as much once their slices are accounted for
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, s.add(record{[]byte("a"), []byte("b")}))
+	}
+
+	assert.NotEmpty(t, s.runs)
+}
+
+func TestExternalSorterCloseRemovesRuns(t *testing.T) {
+	s := newExternalSorter(1, func(a, b record) bool { return false })
+	assert.Nil(t, s.add(record{[]byte("spilled")}))
+	assert.Len(t, s.runs, 1)
+
+	name := s.runs[0].Name()
+	assert.FileExists(t, name)
+
+	s.close()
+	assert.NoFileExists(t, name)
+}
+
+func Test_compareFields(t *testing.T) {
+	a := record{[]byte("x"), nil, []byte("1")}
+	b := record{[]byte("x"), []byte(""), []byte("0")}
+
+	assert.Equal(t, 0, compareFields(a, b, 0))
+	assert.Equal(t, -1, compareFields(a, b, 0, 1))
+	assert.Equal(t, 1, compareFields(b, a, 1, 2))
+	assert.Equal(t, 1, compareFields(a, b, 2))
+}
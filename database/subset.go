@@ -0,0 +1,223 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// foreignKey is a single, possibly composite, foreign key between two tables of the same schema
+type foreignKey struct {
+	name       string
+	table      string
+	columns    []string
+	refTable   string
+	refColumns []string
+}
+
+// subset carries the where rules of a schema across its foreign keys, so the dumped rows are
+// a self-consistent slice of the database. Tables with a where rule are the roots of the slice:
+//   - upward, every table a root references, directly or not, only keeps the rows referenced
+//     by the rows kept in the tables referencing it, along with the ones its own rule selects
+//   - downward, every other table referencing a restricted table only keeps the rows
+//     referencing rows that were kept, or none at all
+//
+// Tables unrelated to any root are dumped whole, which keeps every reference to them valid.
+// Foreign keys of a table to itself are not followed, and neither are the ones closing a cycle
+type subset struct {
+	keys       []foreignKey
+	where      func(table string) (string, bool)
+	qualify    func(table string) string
+	restricted map[string]bool
+	conditions map[string]string
+	visiting   map[string]bool
+}
+
+// prepareSubset computes the conditions of every table of the schema being dumped,
+// which replace its where rules, when subsetting was requested
+func (d *mySQL) prepareSubset(tables []string) error {
+	d.subsetMap = nil
+	if !d.subset {
+		return nil
+	}
+
+	keys, err := d.getForeignKeys()
+	if err != nil {
+		return err
+	}
+
+	d.subsetMap = newSubset(keys, d.ownWhereFor, d.qualify).conditionsFor(tables)
+
+	return nil
+}
+
+func newSubset(keys []foreignKey, where func(string) (string, bool), qualify func(string) string) *subset {
+	return &subset{
+		keys:       keys,
+		where:      where,
+		qualify:    qualify,
+		restricted: make(map[string]bool),
+		conditions: make(map[string]string),
+		visiting:   make(map[string]bool),
+	}
+}
+
+// conditionsFor returns the condition of every table that does not keep all its rows,
+// keyed by the lower case table name
+func (s *subset) conditionsFor(tables []string) map[string]string {
+	var pending []string
+	for _, table := range tables {
+		if _, ok := s.where(table); ok {
+			s.restricted[strings.ToLower(table)] = true
+			pending = append(pending, table)
+		}
+	}
+
+	// every table referenced by a restricted table is restricted as well
+	for len(pending) > 0 {
+		table := pending[0]
+		pending = pending[1:]
+
+		for _, fk := range s.keys {
+			if strings.EqualFold(fk.table, table) && !s.restricted[strings.ToLower(fk.refTable)] {
+				s.restricted[strings.ToLower(fk.refTable)] = true
+				pending = append(pending, fk.refTable)
+			}
+		}
+	}
+
+	conditions := make(map[string]string)
+	for _, table := range tables {
+		if condition, ok := s.condition(table); ok {
+			conditions[strings.ToLower(table)] = condition
+		}
+	}
+
+	return conditions
+}
+
+// condition returns the condition of table, false when it keeps all of its rows
+func (s *subset) condition(table string) (string, bool) {
+	key := strings.ToLower(table)
+	if condition, ok := s.conditions[key]; ok {
+		return condition, true
+	}
+
+	if s.visiting[key] {
+		return "", false
+	}
+
+	s.visiting[key] = true
+	defer delete(s.visiting, key)
+
+	var condition string
+	if s.restricted[key] {
+		condition = s.upwardCondition(table)
+	} else {
+		condition = s.downwardCondition(table)
+	}
+
+	if condition == "" {
+		return "", false
+	}
+
+	s.conditions[key] = condition
+
+	return condition, true
+}
+
+// upwardCondition keeps the rows selected by the where rule of table, along with
+// every row referenced by the rows kept in the restricted tables referencing it
+func (s *subset) upwardCondition(table string) string {
+	var terms []string
+	if where, ok := s.where(table); ok {
+		terms = append(terms, fmt.Sprintf("(%s)", where))
+	}
+
+	for _, fk := range s.keys {
+		if !strings.EqualFold(fk.refTable, table) || strings.EqualFold(fk.table, table) ||
+			!s.restricted[strings.ToLower(fk.table)] {
+			continue
+		}
+
+		// when the referencing table is part of a cycle, all of its references are kept
+		query := fmt.Sprintf("SELECT %s FROM %s", joinColumns(fk.columns), s.qualify(fk.table))
+		if condition, ok := s.condition(fk.table); ok {
+			query = fmt.Sprintf("%s WHERE %s", query, condition)
+		}
+
+		terms = append(terms, fmt.Sprintf("(%s) IN (%s)", joinColumns(fk.refColumns), query))
+	}
+
+	return strings.Join(terms, " OR ")
+}
+
+// downwardCondition keeps the rows of table whose references to restricted tables are all
+// kept. References with a null column do not point to any row, so they are kept as well
+func (s *subset) downwardCondition(table string) string {
+	var terms []string
+	for _, fk := range s.keys {
+		if !strings.EqualFold(fk.table, table) || strings.EqualFold(fk.refTable, table) {
+			continue
+		}
+
+		condition, ok := s.condition(fk.refTable)
+		if !ok {
+			continue
+		}
+
+		var nulls string
+		for _, column := range fk.columns {
+			nulls += fmt.Sprintf("%s IS NULL OR ", quoteIdentifier(column))
+		}
+
+		terms = append(
+			terms,
+			fmt.Sprintf(
+				"(%s(%s) IN (SELECT %s FROM %s WHERE %s))",
+				nulls,
+				joinColumns(fk.columns),
+				joinColumns(fk.refColumns),
+				s.qualify(fk.refTable),
+				condition,
+			),
+		)
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+func joinColumns(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, quoteIdentifier(column))
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// getForeignKeys lists the foreign keys between tables of the schema being dumped
+func (d *mySQL) getForeignKeys() ([]foreignKey, error) {
+	s, err := d.getSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]foreignKey, 0)
+	for _, t := range s.Tables {
+		for _, fk := range t.ForeignKeys {
+			if !strings.EqualFold(fk.ReferencedSchema, s.Name) {
+				continue
+			}
+
+			keys = append(keys, foreignKey{
+				name:       fk.Name,
+				table:      t.Name,
+				columns:    fk.Columns,
+				refTable:   fk.ReferencedTable,
+				refColumns: fk.ReferencedColumns,
+			})
+		}
+	}
+
+	return keys, nil
+}
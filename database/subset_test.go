@@ -0,0 +1,144 @@
+package database
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// shopForeignKeys is a shop where addresses and orders belong to users, orders ship to
+// an address, and each order item references its order and a product
+var shopForeignKeys = []foreignKey{
+	{name: "fk_addresses_user", table: "addresses", columns: []string{"user_id"}, refTable: "users", refColumns: []string{"id"}},
+	{name: "fk_items_order", table: "order_items", columns: []string{"order_id"}, refTable: "orders", refColumns: []string{"id"}},
+	{name: "fk_items_product", table: "order_items", columns: []string{"product_id"}, refTable: "products", refColumns: []string{"id"}},
+	{name: "fk_orders_address", table: "orders", columns: []string{"address_id"}, refTable: "addresses", refColumns: []string{"id"}},
+	{name: "fk_orders_user", table: "orders", columns: []string{"user_id"}, refTable: "users", refColumns: []string{"id"}},
+	{name: "fk_users_referrer", table: "users", columns: []string{"referrer_id"}, refTable: "users", refColumns: []string{"id"}},
+}
+
+var shopTables = []string{"addresses", "order_items", "orders", "products", "users"}
+
+func TestSubsetCarriesRootDownward(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	dumper.whereMap = map[string]string{"users": "id < 5000"}
+
+	conditions := newSubset(shopForeignKeys, dumper.ownWhereFor, dumper.qualify).conditionsFor(shopTables)
+
+	addresses := "(`user_id` IS NULL OR (`user_id`) IN (SELECT `id` FROM `users` WHERE (id < 5000)))"
+	orders := "(`address_id` IS NULL OR (`address_id`) IN (SELECT `id` FROM `addresses` WHERE " + addresses + ")) AND " +
+		"(`user_id` IS NULL OR (`user_id`) IN (SELECT `id` FROM `users` WHERE (id < 5000)))"
+
+	assert.Equal(
+		t,
+		map[string]string{
+			"users":       "(id < 5000)",
+			"addresses":   addresses,
+			"orders":      orders,
+			"order_items": "(`order_id` IS NULL OR (`order_id`) IN (SELECT `id` FROM `orders` WHERE " + orders + "))",
+		},
+		conditions,
+	)
+}
+
+func TestSubsetCarriesRootUpward(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	dumper.schema = "shop"
+	dumper.whereMap = map[string]string{"shop.order_items": "created_at > '2024-01-01'"}
+
+	conditions := newSubset(shopForeignKeys, dumper.ownWhereFor, dumper.qualify).conditionsFor(shopTables)
+
+	items := "(created_at > '2024-01-01')"
+	orders := "(`id`) IN (SELECT `order_id` FROM `shop`.`order_items` WHERE " + items + ")"
+	addresses := "(`id`) IN (SELECT `address_id` FROM `shop`.`orders` WHERE " + orders + ")"
+
+	assert.Equal(
+		t,
+		map[string]string{
+			"order_items": items,
+			"orders":      orders,
+			"addresses":   addresses,
+			"products":    "(`id`) IN (SELECT `product_id` FROM `shop`.`order_items` WHERE " + items + ")",
+			// users are referenced through both the addresses and the orders that are kept
+			"users": "(`id`) IN (SELECT `user_id` FROM `shop`.`addresses` WHERE " + addresses + ") OR " +
+				"(`id`) IN (SELECT `user_id` FROM `shop`.`orders` WHERE " + orders + ")",
+		},
+		conditions,
+	)
+}
+
+func TestSubsetKeepsRowsReferencedByOtherRoots(t *testing.T) {
+	dumper := getInternalMySQLInstance(nil, nil)
+	dumper.whereMap = map[string]string{
+		"users":  "id < 5000",
+		"orders": "total > 100",
+	}
+
+	conditions := newSubset(shopForeignKeys, dumper.ownWhereFor, dumper.qualify).conditionsFor(shopTables)
+
+	orders := "(total > 100)"
+	addresses := "(`id`) IN (SELECT `address_id` FROM `orders` WHERE " + orders + ")"
+
+	assert.Equal(t, orders, conditions["orders"])
+	assert.Equal(t, addresses, conditions["addresses"])
+	assert.Equal(
+		t,
+		"(id < 5000) OR (`id`) IN (SELECT `user_id` FROM `addresses` WHERE "+addresses+") OR "+
+			"(`id`) IN (SELECT `user_id` FROM `orders` WHERE "+orders+")",
+		conditions["users"],
+	)
+	assert.NotContains(t, conditions, "products")
+}
+
+func TestSubsetFollowsCyclesOnce(t *testing.T) {
+	keys := []foreignKey{
+		{name: "fk_a_b", table: "a", columns: []string{"b_id"}, refTable: "b", refColumns: []string{"id"}},
+		{name: "fk_b_a", table: "b", columns: []string{"a_id"}, refTable: "a", refColumns: []string{"id"}},
+	}
+
+	dumper := getInternalMySQLInstance(nil, nil)
+	dumper.whereMap = map[string]string{"a": "id = 1"}
+
+	conditions := newSubset(keys, dumper.ownWhereFor, dumper.qualify).conditionsFor([]string{"a", "b"})
+
+	// b keeps everything a references, whatever rows of a are kept
+	b := "(`id`) IN (SELECT `b_id` FROM `a`)"
+	assert.Equal(
+		t,
+		map[string]string{
+			"a": "(id = 1) OR (`id`) IN (SELECT `a_id` FROM `b` WHERE " + b + ")",
+			"b": b,
+		},
+		conditions,
+	)
+}
+
+func TestMySQLDumpTableDataWithSubset(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.subset = true
+	dumper.whereMap = map[string]string{"users": "id < 5000"}
+
+	expectSchema(
+		mock,
+		map[string][]string{"orders": {"id int"}, "users": {"id int", "tenant_id int"}},
+		foreignKeyRow("fk_orders_user", "orders", "tenant_id", "users", "tenant_id"),
+		foreignKeyRow("fk_orders_user", "orders", "user_id", "users", "id"),
+		// keys to other schemas cannot be followed
+		[]driver.Value{"fk_orders_country", "orders", "country_id", "geo", "countries", "id"},
+	)
+
+	assert.Nil(t, dumper.prepareSubset([]string{"orders", "users"}))
+
+	mock.ExpectQuery("SELECT `id` FROM `orders` WHERE \\(`tenant_id` IS NULL OR `user_id` IS NULL OR " +
+		"\\(`tenant_id`, `user_id`\\) IN \\(SELECT `tenant_id`, `id` FROM `users` WHERE \\(id < 5000\\)\\)\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, dumper.dumpTableData(buffer, "orders"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "INSERT INTO `orders` (`id`) VALUES\n( '1' ),\n( '2' );\n", buffer.String())
+}
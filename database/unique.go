@@ -0,0 +1,324 @@
+package database
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/doutorfinancas/go-mad/generator"
+)
+
+const (
+	// UniqueAttempts is how many times a value already taken by another row of a UNIQUE key
+	// is generated again, before it is made unique with a suffix
+	UniqueAttempts = 10
+	// UniqueSuffixes is how many suffixes are tried on a value before giving up
+	UniqueSuffixes = 1000
+)
+
+// uniqueKey keeps track of the values written to a UNIQUE key, or the primary key, of a table with
+// rewritten columns. Values are kept as 64 bit hashes, so tracking them takes a few bytes per row
+// whatever their size: two values sharing a hash only cost another attempt
+type uniqueKey struct {
+	name string
+	// columns are the positions of the columns of the key among the ones written
+	columns []int
+	// generated are the columns of the key with a rule that generates another value when asked again
+	generated []int
+	seed      maphash.Seed
+	seen      map[uint64]struct{}
+	// suffix is the last suffix handed out, the next ones carry on from it instead of trying
+	// every suffix already taken for every value
+	suffix int
+}
+
+// getUniqueKeysFor returns the keys of the table of rw whose values must stay unique as some of their
+// columns are rewritten. Shuffled columns keep their values, and so their uniqueness
+func (d *mySQL) getUniqueKeysFor(rw *rowWriter) ([]*uniqueKey, error) {
+	t, err := d.getTable(rw.table)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]string, len(t.UniqueKeys)+1)
+	for name, columns := range t.UniqueKeys {
+		keys[name] = columns
+	}
+
+	if len(t.PrimaryKey) > 0 {
+		keys["PRIMARY"] = t.PrimaryKey
+	}
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	positions := make(map[string]int, len(rw.columns))
+	for i, column := range rw.columns {
+		positions[strings.ToLower(strings.Trim(column, "`"))] = i
+	}
+
+	var uniqueKeys []*uniqueKey
+	for _, name := range names {
+		if k := d.newUniqueKey(rw, name, keys[name], positions); k != nil {
+			uniqueKeys = append(uniqueKeys, k)
+		}
+	}
+
+	return uniqueKeys, nil
+}
+
+// hasRewrittenUniqueKeys tells if any column of a UNIQUE key, or of the primary key, of table is rewritten
+// with another rule than a shuffle
+func (d *mySQL) hasRewrittenUniqueKeys(table string) (bool, error) {
+	t, err := d.getTable(table)
+	if err != nil {
+		return false, err
+	}
+
+	keys := [][]string{t.PrimaryKey}
+	for _, columns := range t.UniqueKeys {
+		keys = append(keys, columns)
+	}
+
+	rules := d.selectFor(table)
+	for _, columns := range keys {
+		for _, column := range columns {
+			if rule, ok := rules[strings.ToLower(column)]; ok && !isShuffle(rule) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// newUniqueKey returns nil for keys that need no tracking, as none of their columns is rewritten,
+// or some of them are not written, such as generated columns
+func (d *mySQL) newUniqueKey(rw *rowWriter, name string, columns []string, positions map[string]int) *uniqueKey {
+	k := &uniqueKey{name: name, seed: maphash.MakeSeed(), seen: make(map[uint64]struct{})}
+
+	rewritten := false
+	for _, column := range columns {
+		i, ok := positions[strings.ToLower(column)]
+		if !ok {
+			return nil
+		}
+
+		k.columns = append(k.columns, i)
+
+		rule, ok := d.selectFor(rw.table)[strings.ToLower(column)]
+		if !ok || isShuffle(rule) {
+			continue
+		}
+
+		rewritten = true
+		if (rw.fakers != nil && rw.fakers[i] != nil) || (rw.transforms != nil && rw.transforms[i] != nil) ||
+			(rw.templates != nil && rw.templates[i] != nil) {
+			k.generated = append(k.generated, i)
+		}
+	}
+
+	if !rewritten {
+		return nil
+	}
+
+	return k
+}
+
+// hash returns the hash of the values of the key in vals, and false when they hold NULL, as any number of
+// rows may have it. Strings are compared as the default utf8mb4_0900_ai_ci collation does, ignoring their case
+// and the accents of latin letters, so João and joao are the same value
+func (k *uniqueKey) hash(vals []string) (uint64, bool) {
+	var h maphash.Hash
+	h.SetSeed(k.seed)
+
+	for _, i := range k.columns {
+		if vals[i] == "NULL" || vals[i] == "DEFAULT" {
+			return 0, false
+		}
+
+		v := generator.FoldAccents(vals[i])
+		if isQuoted(v) {
+			v = strings.TrimRight(v[:len(v)-1], " ")
+		}
+
+		_, _ = h.WriteString(v)
+		_ = h.WriteByte(0)
+	}
+
+	return h.Sum64(), true
+}
+
+func (k *uniqueKey) taken(vals []string) bool {
+	h, ok := k.hash(vals)
+	if !ok {
+		return false
+	}
+
+	_, taken := k.seen[h]
+
+	return taken
+}
+
+func (k *uniqueKey) add(vals []string) {
+	if h, ok := k.hash(vals); ok {
+		k.seen[h] = struct{}{}
+	}
+}
+
+// makeUnique changes the rewritten values of vals taken by a previous row of a UNIQUE key. They are
+// generated again up to UniqueAttempts times, then strings get a suffix, before any @ so that emails
+// stay valid. The row fails when no unique value can be found, which its restore would fail with
+func (d *mySQL) makeUnique(rw *rowWriter, vals []string) error {
+	if rw.uniqueKeys == nil {
+		return nil
+	}
+
+	// the values suffixes are added to, once generating them again gave up
+	var bases map[int]string
+
+	for attempt := 1; ; attempt++ {
+		k := firstTaken(rw.uniqueKeys, vals)
+		if k == nil {
+			break
+		}
+
+		if attempt <= UniqueAttempts && len(k.generated) > 0 {
+			for _, i := range k.generated {
+				val, err := d.getColumnValue(rw, i, rw.row[i], attempt)
+				if err != nil {
+					return err
+				}
+
+				vals[i] = val
+			}
+
+			continue
+		}
+
+		if attempt > UniqueAttempts+UniqueSuffixes {
+			return fmt.Errorf(
+				"key %s of table %s: no unique value was found after %d attempts", k.name, rw.table, attempt-1,
+			)
+		}
+
+		i, ok := suffixable(k, vals)
+		if !ok {
+			return fmt.Errorf(
+				"key %s of table %s: %s is already taken and cannot be made unique",
+				k.name, rw.table, strings.Join(keyValues(k, vals), ", "),
+			)
+		}
+
+		if bases == nil {
+			bases = make(map[int]string)
+		}
+
+		if _, ok = bases[i]; !ok {
+			bases[i] = vals[i]
+		}
+
+		k.suffix++
+		vals[i] = withSuffix(bases[i], k.suffix)
+
+		if err := d.checkLength(rw, i, vals[i]); err != nil {
+			return fmt.Errorf("key %s of table %s: %w", k.name, rw.table, err)
+		}
+	}
+
+	for _, k := range rw.uniqueKeys {
+		k.add(vals)
+	}
+
+	return nil
+}
+
+func firstTaken(keys []*uniqueKey, vals []string) *uniqueKey {
+	for _, k := range keys {
+		if k.taken(vals) {
+			return k
+		}
+	}
+
+	return nil
+}
+
+// suffixable returns the first column of the key holding a string, preferring the generated ones
+func suffixable(k *uniqueKey, vals []string) (int, bool) {
+	for _, columns := range [][]int{k.generated, k.columns} {
+		for _, i := range columns {
+			if isQuoted(vals[i]) {
+				return i, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func keyValues(k *uniqueKey, vals []string) []string {
+	values := make([]string, 0, len(k.columns))
+	for _, i := range k.columns {
+		values = append(values, vals[i])
+	}
+
+	return values
+}
+
+// withSuffix adds -n to a quoted string, before its last @ when it has one
+func withSuffix(val string, n int) string {
+	inner := val[1 : len(val)-1]
+	suffix := "-" + strconv.Itoa(n)
+
+	if at := strings.LastIndexByte(inner, '@'); at > 0 {
+		return "'" + inner[:at] + suffix + inner[at:] + "'"
+	}
+
+	return "'" + inner + suffix + "'"
+}
+
+// checkLength fails when a suffixed value no longer fits in its column, as the restore would truncate it
+func (d *mySQL) checkLength(rw *rowWriter, i int, val string) error {
+	t, err := d.getTable(rw.table)
+	if err != nil {
+		return err
+	}
+
+	name := strings.Trim(rw.columns[i], "`")
+
+	c, ok := t.Column(name)
+	if !ok || c.Length == 0 {
+		return nil
+	}
+
+	if n := unescapedLength(val[1 : len(val)-1]); int64(n) > c.Length {
+		return fmt.Errorf("no unique value fits in column %s, of %d characters", name, c.Length)
+	}
+
+	return nil
+}
+
+// unescapedLength counts the characters of an escaped string, each escape sequence being a single one
+func unescapedLength(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+		}
+
+		n++
+	}
+
+	return n - (len(s) - utf8.RuneCountInString(s))
+}
+
+func isQuoted(val string) bool {
+	return len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\''
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/doutorfinancas/go-mad/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func uniqueKeyRow(name, table, column string) []driver.Value {
+	return []driver.Value{name, table, column, nil, nil, nil}
+}
+
+func TestMySQLDumpTableDataWithUniqueKeys(t *testing.T) {
+	db, mock := getDB(t)
+	buffer := bytes.NewBuffer(make([]byte, 0))
+
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{
+		"users": {
+			"email": "tmpl:{{.Value | lower}}@example.test",
+			"login": "'FAKE'",
+			"notes": "tmpl:same",
+		},
+	}
+
+	expectSchema(
+		mock,
+		map[string][]string{"users": {"id int", "email", "login", "notes"}},
+		uniqueKeyRow("uq_email", "users", "email"),
+		uniqueKeyRow("uq_login", "users", "login"),
+	)
+
+	mock.ExpectQuery("SELECT `id`, `email`, 'FAKE' AS `login`, `notes` FROM `users`$").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT", 0),
+			sqlmock.NewColumn("email").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("login").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("notes").OfType("VARCHAR", ""),
+		).
+			AddRow(1, "Ann", "FAKE", "a").
+			AddRow(2, "ANN", "FAKE", "b").
+			AddRow(3, "aNn", "FAKE", "c").
+			AddRow(4, nil, "FAKE", "d"),
+	)
+
+	// values taken by another row get a suffix, before the @ of emails, NULL values are never taken
+	// and columns without a UNIQUE key are left alone
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		"INSERT INTO `users` (`id`, `email`, `login`, `notes`) VALUES\n"+
+			"( 1, 'ann@example.test', 'FAKE', 'same' ),\n"+
+			"( 2, 'ann-1@example.test', 'FAKE-1', 'same' ),\n"+
+			"( 3, 'ann-2@example.test', 'FAKE-2', 'same' ),\n"+
+			"( 4, NULL, 'FAKE-3', 'same' );\n",
+		buffer.String(),
+	)
+}
+
+func TestMySQLDumpTableDataWithUniqueKeysFoldsAccents(t *testing.T) {
+	db, mock := getDB(t)
+	buffer := bytes.NewBuffer(make([]byte, 0))
+
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{"users": {"login": "tmpl:{{.Value}}"}}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "login"}}, uniqueKeyRow("uq_login", "users", "login"))
+
+	mock.ExpectQuery("SELECT `id`, `login` FROM `users`$").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT", 0),
+			sqlmock.NewColumn("login").OfType("VARCHAR", ""),
+		).
+			AddRow(1, "José").
+			AddRow(2, "jose"),
+	)
+
+	// utf8mb4_0900_ai_ci restores both as the same value
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "INSERT INTO `users` (`id`, `login`) VALUES\n( 1, 'José' ),\n( 2, 'jose-1' );\n", buffer.String())
+}
+
+func TestMySQLDumpTableDataWithUniqueKeysGeneratesValuesAgain(t *testing.T) {
+	db, mock := getDB(t)
+	buffer := bytes.NewBuffer(make([]byte, 0))
+
+	dumper := getInternalMySQLInstance(db, generator.NewSeededService(1))
+	dumper.selectMap = map[string]map[string]string{"users": {"code": "go:random(1, 3)"}}
+
+	expectSchema(mock, map[string][]string{"users": {"id int", "code int"}}, uniqueKeyRow("uq_code", "users", "code"))
+
+	mock.ExpectQuery("SELECT `id`, `code` FROM `users`$").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT", 0),
+			sqlmock.NewColumn("code").OfType("INT", 0),
+		).
+			AddRow(1, 7).
+			AddRow(2, 7).
+			AddRow(3, 7),
+	)
+
+	assert.Nil(t, dumper.dumpTableData(buffer, "users"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	codes := regexp.MustCompile(`\( \d, (\d) \)`).FindAllStringSubmatch(buffer.String(), -1)
+	got := make([]string, 0, len(codes))
+	for _, code := range codes {
+		got = append(got, code[1])
+	}
+
+	sort.Strings(got)
+	assert.Equal(t, []string{"1", "2", "3"}, got)
+}
+
+func TestMySQLDumpTableDataWithUniqueKeysFails(t *testing.T) {
+	tests := []struct {
+		name       string
+		column     string
+		columnType string
+		rule       string
+		length     int64
+		expected   string
+	}{
+		{
+			name:       "numbers cannot get a suffix",
+			column:     "code int",
+			columnType: "INT",
+			rule:       "go:random(1, 1)",
+			expected:   "key uq_code of table users: 1 is already taken and cannot be made unique",
+		},
+		{
+			name:       "suffixes must fit in the column",
+			column:     "code",
+			columnType: "VARCHAR",
+			rule:       "tmpl:{{.Value}}",
+			length:     5,
+			expected:   "key uq_code of table users: no unique value fits in column code, of 5 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := getDB(t)
+			dumper := getInternalMySQLInstance(db, generator.NewService())
+			dumper.selectMap = map[string]map[string]string{"users": {"code": tt.rule}}
+
+			s := newTestSchema(map[string][]string{"users": {"id int", tt.column}})
+			users, _ := s.Table("users")
+			users.UniqueKeys["uq_code"] = []string{"code"}
+			code, _ := users.Column("code")
+			code.Length = tt.length
+			dumper.schemas[""] = s
+
+			mock.ExpectQuery("SELECT `id`, `code` FROM `users`$").WillReturnRows(
+				sqlmock.NewRowsWithColumnDefinition(
+					sqlmock.NewColumn("id").OfType("INT", 0),
+					sqlmock.NewColumn("code").OfType(tt.columnType, ""),
+				).
+					AddRow(1, "abcde").
+					AddRow(2, "abcde"),
+			)
+
+			assert.EqualError(t, dumper.dumpTableData(new(bytes.Buffer), "users"), tt.expected)
+		})
+	}
+}
+
+func Test_withSuffix(t *testing.T) {
+	assert.Equal(t, "'jane.doe-2@example.test'", withSuffix("'jane.doe@example.test'", 2))
+	assert.Equal(t, "'a@b-3@example.test'", withSuffix("'a@b@example.test'", 3))
+	assert.Equal(t, "'@handle-1'", withSuffix("'@handle'", 1))
+	assert.Equal(t, "'O\\'Neil-1'", withSuffix("'O\\'Neil'", 1))
+}
+
+func Test_unescapedLength(t *testing.T) {
+	assert.Equal(t, 6, unescapedLength("O\\'Neil"))
+	assert.Equal(t, 4, unescapedLength("João"))
+	assert.Equal(t, 2, unescapedLength("\\\\\\n"))
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/doutorfinancas/go-mad/generator"
+	"github.com/gobwas/glob"
+)
+
+// Problem is a rule of the configuration that does not match the schema it is checked against
+type Problem struct {
+	// Rule is where the rule is in the configuration, as in rewrite.users.email
+	Rule    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Rule + ": " + p.Message
+}
+
+// validation gathers the problems found over every schema, along with the rules that applied to any table
+type validation struct {
+	problems []Problem
+	matched  map[string]bool
+}
+
+func (v *validation) add(rule, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the rewrite and where rules of the dumper, along with the nodata and ignore globs, against
+// the schemas it would dump: every table and column must exist, every glob must match a table, faker
+// expressions, transforms and templates must compile, and SQL rewrites and where clauses must be accepted
+// by EXPLAIN. It only fails when the schemas cannot be read
+func (d *mySQL) Validate(noData, ignore []string) ([]Problem, error) {
+	databases, err := d.getDatabases()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(databases) == 0 {
+		// the schema selected by the connection
+		databases = []string{""}
+	}
+
+	defer func() {
+		d.schema = ""
+	}()
+
+	globs := map[string][]string{NoDataMapPlacement: noData, IgnoreMapPlacement: ignore}
+	v := &validation{matched: make(map[string]bool)}
+
+	for _, placement := range []string{NoDataMapPlacement, IgnoreMapPlacement} {
+		for _, pattern := range globs[placement] {
+			if _, gErr := glob.Compile(pattern); gErr != nil {
+				v.add(placement, "%s is not a valid pattern: %v", pattern, gErr)
+				v.matched[placement+"."+pattern] = true
+			}
+		}
+	}
+
+	for _, database := range databases {
+		d.schema = database
+		if err = d.validateSchema(v, globs); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range sortedKeys(d.selectMap) {
+		v.unmatched("rewrite", key)
+	}
+
+	for _, key := range sortedKeys(d.whereMap) {
+		v.unmatched("where", key)
+	}
+
+	for _, placement := range []string{NoDataMapPlacement, IgnoreMapPlacement} {
+		for _, pattern := range globs[placement] {
+			if !v.matched[placement+"."+pattern] {
+				v.add(placement, "%s matches no table", pattern)
+			}
+		}
+	}
+
+	return v.problems, nil
+}
+
+// unmatched reports a rule that applied to no table, which is usually a typo
+func (v *validation) unmatched(section, key string) {
+	switch {
+	case v.matched[section+"."+key]:
+	case key != strings.ToLower(key):
+		v.add(section+"."+key, "tables are matched in lower case, use %s", strings.ToLower(key))
+	default:
+		v.add(section+"."+key, "table %s not found", key)
+	}
+}
+
+func (d *mySQL) validateSchema(v *validation, globs map[string][]string) error {
+	tables, views, err := d.getTablesAndViews()
+	if err != nil {
+		return err
+	}
+
+	for placement, patterns := range globs {
+		candidates := tables
+		if placement == IgnoreMapPlacement {
+			candidates = append(append([]string{}, tables...), views...)
+		}
+
+		for _, pattern := range patterns {
+			if !v.matched[placement+"."+pattern] && len(d.listTables(candidates, []string{pattern})) > 0 {
+				v.matched[placement+"."+pattern] = true
+			}
+		}
+	}
+
+	rewritten := func(key string) bool {
+		return d.selectMap[key] != nil
+	}
+
+	filtered := func(key string) bool {
+		_, ok := d.whereMap[key]
+		return ok
+	}
+
+	// the rows of views are never dumped, so neither rule applies to them
+	for _, view := range views {
+		for section, exists := range map[string]func(string) bool{"rewrite": rewritten, "where": filtered} {
+			if key, ok := d.ruleKey(view, exists); ok {
+				v.matched[section+"."+key] = true
+				v.add(section+"."+key, "%s%s is a view, whose rows are never dumped", d.inSchema(), view)
+			}
+		}
+	}
+
+	for _, table := range tables {
+		if key, ok := d.ruleKey(table, rewritten); ok {
+			v.matched["rewrite."+key] = true
+			if err = d.validateRewrites(v, "rewrite."+key, table, d.selectMap[key]); err != nil {
+				return err
+			}
+		}
+
+		if key, ok := d.ruleKey(table, filtered); ok {
+			v.matched["where."+key] = true
+
+			query := fmt.Sprintf("SELECT 1 FROM %s WHERE %s", d.qualify(table), d.whereMap[key])
+			if eErr := d.explain(query); eErr != nil {
+				v.add("where."+key, "%s%v", d.inSchema(), eErr)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ruleKey returns the key of the rules of table, the schema qualified one taking precedence
+func (d *mySQL) ruleKey(table string, exists func(key string) bool) (string, bool) {
+	for _, key := range d.ruleKeys(table) {
+		if exists(key) {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+func (d *mySQL) validateRewrites(v *validation, section, table string, rules map[string]string) error {
+	t, err := d.getTable(table)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range sortedKeys(rules) {
+		c, ok := t.Column(column)
+
+		switch {
+		case !ok:
+			v.add(section+"."+column, "%scolumn %s not found in table %s", d.inSchema(), column, table)
+		case column != strings.ToLower(column):
+			v.add(section+"."+column, "columns are matched in lower case, use %s", strings.ToLower(column))
+		case c.Generated:
+			v.add(
+				section+"."+column, "%scolumn %s of table %s is generated, so it is never dumped", d.inSchema(), column, table,
+			)
+		default:
+			if rErr := d.validateRewrite(t, c.Name, rules[column]); rErr != nil {
+				v.add(section+"."+column, "%s%v", d.inSchema(), rErr)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateRewrite compiles a rewrite as it is compiled when the table is dumped,
+// SQL rewrites are checked by the server
+func (d *mySQL) validateRewrite(t *Table, column, rule string) error {
+	service := d.generatorFor(t.Name, column)
+
+	var err error
+	switch expression, faker := fakerExpression(rule); {
+	case faker:
+		_, err = service.Compile(expression)
+	case generator.IsTransform(rule):
+		_, err = service.Transform(rule)
+	case generator.IsTemplate(rule):
+		_, err = service.Template(rule)
+	case isShuffle(rule):
+		if len(t.PrimaryKey) == 0 {
+			return fmt.Errorf("table %s has shuffled columns, which requires a primary key", t.Name)
+		}
+
+		if by := shuffleGroup(rule); by != "" {
+			if _, ok := t.Column(by); !ok {
+				return fmt.Errorf("column %s, which values are shuffled by, not found in table %s", by, t.Name)
+			}
+		}
+	default:
+		err = d.explain(fmt.Sprintf("SELECT %s FROM %s", rule, d.qualify(t.Name)))
+	}
+
+	return err
+}
+
+// explain has the server parse and plan query, without running it
+func (d *mySQL) explain(query string) error {
+	rows, err := d.conn.QueryContext(context.Background(), "EXPLAIN "+query)
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// inSchema prefixes the problems of a schema when several are dumped, as the same rule may apply to all of them
+func (d *mySQL) inSchema() string {
+	if d.schema == "" {
+		return ""
+	}
+
+	return "in schema " + d.schema + ", "
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/doutorfinancas/go-mad/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLValidate(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.selectMap = map[string]map[string]string{
+		"users": {
+			"email":     "faker.Internet().Emial()",
+			"emial":     "'x'",
+			"name":      "UPPER(name)",
+			"full_name": "'x'",
+			"Phone":     "go:hash",
+			"notes":     "tmpl:{{shout}}",
+		},
+		"orders":  {"total": "shuffle"},
+		"Users":   {"email": "go:hash"},
+		"ghosts":  {"name": "go:hash"},
+		"v_users": {"name": "go:hash"},
+	}
+	dumper.whereMap = map[string]string{"users": "id < 10", "orders": "totl > 1"}
+
+	mock.ExpectQuery("SHOW FULL TABLES").WillReturnRows(
+		sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+			AddRow("orders", "BASE TABLE").
+			AddRow("users", "BASE TABLE").
+			AddRow("v_users", "VIEW"),
+	)
+
+	expectSchema(
+		mock,
+		map[string][]string{
+			"orders":  {"id int", "total int"},
+			"users":   {"id int", "email", "name", "full_name varchar VIRTUAL GENERATED", "phone", "notes"},
+			"v_users": {"name"},
+		},
+		primaryKey("users", "id")...,
+	)
+
+	mock.ExpectQuery("EXPLAIN SELECT 1 FROM `orders` WHERE totl > 1").
+		WillReturnError(errors.New("Unknown column 'totl' in 'where clause'"))
+	mock.ExpectQuery("EXPLAIN SELECT UPPER\\(name\\) FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("EXPLAIN SELECT 1 FROM `users` WHERE id < 10").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	problems, err := dumper.Validate([]string{"order*", "tmp_*"}, []string{"v_*", "[a"})
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	got := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		got = append(got, problem.String())
+	}
+
+	assert.Equal(
+		t,
+		[]string{
+			"ignore: [a is not a valid pattern: unexpected end of input",
+			"rewrite.v_users: v_users is a view, whose rows are never dumped",
+			"rewrite.orders.total: table orders has shuffled columns, which requires a primary key",
+			"where.orders: Unknown column 'totl' in 'where clause'",
+			"rewrite.users.Phone: columns are matched in lower case, use phone",
+			"rewrite.users.email: faker expression \"faker.Internet().Emial()\", at position 18: " +
+				"faker.Internet has no method Emial",
+			"rewrite.users.emial: column emial not found in table users",
+			"rewrite.users.full_name: column full_name of table users is generated, so it is never dumped",
+			"rewrite.users.notes: template \"tmpl:{{shout}}\": template: tmpl:1: function \"shout\" not defined",
+			"rewrite.Users: tables are matched in lower case, use users",
+			"rewrite.ghosts: table ghosts not found",
+			"nodata: tmp_* matches no table",
+		},
+		got,
+	)
+}
+
+func TestMySQLValidateSeveralDatabases(t *testing.T) {
+	db, mock := getDB(t)
+	dumper := getInternalMySQLInstance(db, generator.NewService())
+	dumper.databases = []string{"billing", "shop"}
+	dumper.whereMap = map[string]string{"users": "id < 10", "billing.users": "id < 100"}
+
+	for _, database := range dumper.databases {
+		mock.ExpectQuery("SHOW FULL TABLES FROM `" + database + "`").WillReturnRows(
+			sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).AddRow("users", "BASE TABLE"),
+		)
+
+		where := "id < 10"
+		if database == "billing" {
+			where = "id < 100"
+		}
+
+		// the schema qualified rule takes precedence over the bare one
+		mock.ExpectQuery("EXPLAIN SELECT 1 FROM `" + database + "`.`users` WHERE " + where).
+			WillReturnError(errors.New("denied"))
+	}
+
+	problems, err := dumper.Validate(nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(
+		t,
+		[]Problem{
+			{Rule: "where.billing.users", Message: "in schema billing, denied"},
+			{Rule: "where.users", Message: "in schema shop, denied"},
+		},
+		problems,
+	)
+}
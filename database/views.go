@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// dumpSchemaViews writes views the same way mysqldump does, first a placeholder
+// view with the same columns for every view, so views can reference each other,
+// and only then the real definitions, ordered by their dependencies
+func (d *mySQL) dumpSchemaViews(w io.Writer, views []string) error {
+	pending, definitions, err := d.getViewDefinitions(views)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	for _, view := range pending {
+		placeholder, err := d.getViewPlaceholder(view)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(w, placeholder); err != nil {
+			return err
+		}
+	}
+
+	for _, view := range sortViewsByDependency(pending, definitions) {
+		if _, err := io.WriteString(w, getFinalViewStatement(view, definitions[view])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getViewDefinitions returns the views that are not ignored, along with their definitions
+func (d *mySQL) getViewDefinitions(views []string) ([]string, map[string]string, error) {
+	var pending []string
+	for _, view := range views {
+		if !d.isTableIgnored(view) {
+			pending = append(pending, view)
+		}
+	}
+
+	definitions := make(map[string]string, len(pending))
+	for _, view := range pending {
+		ddl, err := d.getCreateViewStatement(view)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		definitions[view] = ddl
+	}
+
+	return pending, definitions, nil
+}
+
+func getFinalViewStatement(view, ddl string) string {
+	s := fmt.Sprintf("\n--\n-- Final view structure for view `%s`\n--\n\n", view)
+	s += fmt.Sprintf("DROP VIEW IF EXISTS `%s`;\n", view)
+	s += fmt.Sprintf("%s;\n", ddl)
+
+	return s
+}
+
+func (d *mySQL) getCreateViewStatement(view string) (string, error) {
+	var name, ddl, charset, collation string
+
+	row := d.useTransactionOrDBQueryRow(fmt.Sprintf("SHOW CREATE VIEW %s", d.qualify(view)))
+	if err := row.Scan(&name, &ddl, &charset, &collation); err != nil {
+		return "", err
+	}
+
+	return d.stripDefiner(ddl), nil
+}
+
+// getViewPlaceholder returns a temporary view exposing the same columns as view,
+// which allows other views to be created before its real definition exists
+func (d *mySQL) getViewPlaceholder(view string) (string, error) {
+	columns, err := d.getViewColumns(view)
+	if err != nil {
+		return "", err
+	}
+
+	fields := make([]string, 0, len(columns))
+	for _, column := range columns {
+		fields = append(fields, fmt.Sprintf(" 1 AS %s", quoteIdentifier(column)))
+	}
+
+	s := fmt.Sprintf("\n--\n-- Temporary view structure for view `%s`\n--\n\n", view)
+	s += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", view)
+	s += fmt.Sprintf("DROP VIEW IF EXISTS `%s`;\n", view)
+	s += fmt.Sprintf("CREATE VIEW `%s` AS SELECT\n%s;\n", view, strings.Join(fields, ",\n"))
+
+	return s, nil
+}
+
+func (d *mySQL) getViewColumns(view string) ([]string, error) {
+	columns := make([]string, 0)
+
+	rows, err := d.conn.QueryContext(
+		context.Background(),
+		fmt.Sprintf(
+			"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = %s AND TABLE_NAME = '%s' ORDER BY ORDINAL_POSITION",
+			d.schemaExpression(),
+			escape(view),
+		),
+	)
+	if a := d.evaluateErrors(err, rows); a != nil {
+		return columns, a
+	}
+
+	defer func(rows *sql.Rows) {
+		dErr := rows.Close()
+		if dErr != nil {
+			d.log.Error(
+				dErr.Error(),
+				zap.String("internal", "failed to close rows while getting view columns"),
+			)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var column string
+
+		if dErr := rows.Scan(&column); dErr != nil {
+			return columns, dErr
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// sortViewsByDependency orders views so that any view comes after the views it
+// selects from. A view depends on another when its definition references it,
+// references that would form a cycle are simply not followed
+func sortViewsByDependency(views []string, definitions map[string]string) []string {
+	dependencies := make(map[string][]string, len(views))
+	for _, view := range views {
+		for _, other := range views {
+			if other != view && strings.Contains(definitions[view], "`"+other+"`") {
+				dependencies[view] = append(dependencies[view], other)
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(views))
+	visited := make(map[string]bool, len(views))
+	visiting := make(map[string]bool)
+
+	var visit func(view string)
+	visit = func(view string) {
+		if visited[view] || visiting[view] {
+			return
+		}
+
+		visiting[view] = true
+		for _, dep := range dependencies[view] {
+			visit(dep)
+		}
+		visiting[view] = false
+
+		visited[view] = true
+		sorted = append(sorted, view)
+	}
+
+	for _, view := range views {
+		visit(view)
+	}
+
+	return sorted
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sortViewsByDependency(t *testing.T) {
+	definitions := map[string]string{
+		"a_report":  "CREATE VIEW `a_report` AS select `db`.`b_summary`.`id` AS `id` from `db`.`b_summary`",
+		"b_summary": "CREATE VIEW `b_summary` AS select `db`.`c_base`.`id` AS `id` from `db`.`c_base`",
+		"c_base":    "CREATE VIEW `c_base` AS select `db`.`users`.`id` AS `id` from `db`.`users`",
+		"d_alone":   "CREATE VIEW `d_alone` AS select 1 AS `one`",
+	}
+
+	assert.Equal(
+		t,
+		[]string{"c_base", "b_summary", "a_report", "d_alone"},
+		sortViewsByDependency([]string{"a_report", "b_summary", "c_base", "d_alone"}, definitions),
+	)
+
+	// a cycle must not hang nor drop any view
+	cycle := map[string]string{
+		"x": "select * from `y`",
+		"y": "select * from `x`",
+	}
+	assert.ElementsMatch(t, []string{"x", "y"}, sortViewsByDependency([]string{"x", "y"}, cycle))
+}
+
+func Test_mySQL_dumpsViewsAfterTables(t *testing.T) {
+	db, mock := getDB(t)
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SHOW FULL TABLES").WillReturnRows(
+			sqlmock.NewRows([]string{"Tables_in_database", "Table_type"}).
+				AddRow("active_users", "VIEW").
+				AddRow("old_users", "VIEW").
+				AddRow("users", "BASE TABLE").
+				AddRow("users_v", "VIEW"),
+		)
+	}
+	mock.ExpectQuery("SHOW CREATE TABLE `users`").WillReturnRows(
+		sqlmock.NewRows([]string{"Table", "Create Table"}).
+			AddRow("users", "CREATE TABLE `users` (`id` int(11) NOT NULL, `name` varchar(20))"),
+	)
+	mock.ExpectQuery("SHOW CREATE VIEW `active_users`").WillReturnRows(
+		sqlmock.NewRows([]string{"View", "Create View", "character_set_client", "collation_connection"}).AddRow(
+			"active_users",
+			"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `active_users` AS "+
+				"select `db`.`users_v`.`id` AS `id` from `db`.`users_v`",
+			"utf8mb4",
+			"utf8mb4_general_ci",
+		),
+	)
+	mock.ExpectQuery("SHOW CREATE VIEW `users_v`").WillReturnRows(
+		sqlmock.NewRows([]string{"View", "Create View", "character_set_client", "collation_connection"}).AddRow(
+			"users_v",
+			"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `users_v` AS "+
+				"select `db`.`users`.`id` AS `id`,`db`.`users`.`name` AS `name` from `db`.`users`",
+			"utf8mb4",
+			"utf8mb4_general_ci",
+		),
+	)
+	mock.ExpectQuery("SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE\\(\\) AND TABLE_NAME = 'active_users'").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+	mock.ExpectQuery("SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE\\(\\) AND TABLE_NAME = 'users_v'").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("name"))
+
+	dumper := getInternalMySQLInstance(db, nil)
+	dumper.skipDefiner = true
+
+	assert.Nil(t, dumper.SetFilterMap([]string{"users"}, []string{"old_*"}))
+
+	b := new(strings.Builder)
+	assert.Nil(t, dumper.Dump(b))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	out := b.String()
+	assert.NotContains(t, out, "old_users")
+	assert.NotContains(t, out, "DEFINER=")
+	assert.Contains(t, out, "CREATE VIEW `users_v` AS SELECT\n 1 AS `id`,\n 1 AS `name`;\n")
+	assert.Contains(t, out, "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `users_v` AS")
+
+	// views come after the tables, placeholders first and then the final views in dependency order
+	table := strings.Index(out, "Structure for table `users`")
+	firstPlaceholder := strings.Index(out, "Temporary view structure")
+	lastPlaceholder := strings.LastIndex(out, "Temporary view structure")
+	finalUsers := strings.Index(out, "Final view structure for view `users_v`")
+	finalActive := strings.Index(out, "Final view structure for view `active_users`")
+	assert.True(t, table < firstPlaceholder)
+	assert.True(t, lastPlaceholder < finalUsers)
+	assert.True(t, finalUsers < finalActive)
+	assert.True(t, finalActive < strings.Index(out, "SET FOREIGN_KEY_CHECKS = 1;"))
+}
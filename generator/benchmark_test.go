@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"strconv"
+	"testing"
+)
+
+var benchmarkExpressions = []string{
+	"faker.Person().Name()",
+	"faker.Internet().Email()",
+	"faker.Lorem().Sentence(5)",
+	"faker.Numerify('###-###-###')",
+	"faker.Time().TimeBetween('2000-01-01', '2020-12-31').Format('2006-01-02')",
+	"faker.Locale(pt).NIF()",
+}
+
+// BenchmarkFaker compares parsing a faker expression for every value, as
+// ReplaceStringWithFakerWhenRequested does, with compiling it once and calling it for every value
+func BenchmarkFaker(b *testing.B) {
+	for _, expression := range benchmarkExpressions {
+		b.Run(expression+"/parsed", func(b *testing.B) {
+			s := NewSeededService(42)
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := s.ReplaceStringWithFakerWhenRequested(expression); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(expression+"/compiled", func(b *testing.B) {
+			generate, err := NewSeededService(42).Compile(expression)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err = generate(nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDeterministicFaker is BenchmarkFaker for deterministic services, which seed a faker for every value
+func BenchmarkDeterministicFaker(b *testing.B) {
+	originals := make([][]byte, 1024)
+	for i := range originals {
+		originals[i] = []byte("user" + strconv.Itoa(i) + "@example.com")
+	}
+
+	s := NewDeterministicService([]byte("secret"))
+
+	b.Run("parsed", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := s.ReplaceStringWithFakerFor("faker.Internet().Email()", originals[i%len(originals)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		generate, err := s.Compile("faker.Internet().Email()")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if _, err = generate(originals[i%len(originals)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkTransform measures a go: pipeline, which is compiled once as well
+func BenchmarkTransform(b *testing.B) {
+	transform, err := ParseTransform("go:keep_if_empty|mask(keep_last=4)|hash")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	value := Value{Bytes: []byte("4111111111111111"), Type: "VARCHAR"}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err = transform.Apply(value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
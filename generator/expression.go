@@ -0,0 +1,693 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+// FakerRoot is the receiver every faker expression starts from, as in faker.Person().Name()
+const FakerRoot = "faker"
+
+// timeLayouts are the layouts string literals are parsed with, when they are passed as a time.Time
+var timeLayouts = []string{time.RFC3339Nano, dateTimeLayout, dateLayout}
+
+// ExpressionError is a faker expression that cannot be parsed or evaluated, along with the
+// position, counted in characters from 1, where the problem was found
+type ExpressionError struct {
+	Expression string
+	Position   int
+	Message    string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("faker expression %q, at position %d: %s", e.Expression, e.Position, e.Message)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenInt
+	tokenFloat
+	tokenDot
+	tokenComma
+	tokenOpen
+	tokenClose
+)
+
+func (k tokenKind) String() string {
+	return [...]string{
+		"end of expression", "identifier", "string", "integer", "number", ".", ",", "(", ")",
+	}[k]
+}
+
+type token struct {
+	kind tokenKind
+	// text is the source of the token, or the unquoted value of strings
+	text string
+	// pos is the byte offset of the token in the expression
+	pos int
+}
+
+// literal is an argument of a call, whose value is converted to the type of its parameter when called
+type literal struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// call is a method call of a chain, such as Sentence(5)
+type call struct {
+	name string
+	args []literal
+	pos  int
+}
+
+// expression is a parsed faker expression, a chain of calls starting from faker
+type expression struct {
+	source string
+	calls  []call
+}
+
+// lexer splits a faker expression into tokens
+type lexer struct {
+	source string
+	pos    int
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	return &ExpressionError{
+		Expression: l.source,
+		Position:   utf8.RuneCountInString(l.source[:pos]) + 1,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.source) && unicode.IsSpace(rune(l.source[l.pos])) {
+		l.pos++
+	}
+
+	start := l.pos
+	if start == len(l.source) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	c, size := utf8.DecodeRuneInString(l.source[start:])
+	switch {
+	case c == '.':
+		l.pos++
+		return token{kind: tokenDot, text: ".", pos: start}, nil
+	case c == ',':
+		l.pos++
+		return token{kind: tokenComma, text: ",", pos: start}, nil
+	case c == '(':
+		l.pos++
+		return token{kind: tokenOpen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokenClose, text: ")", pos: start}, nil
+	case c == '"' || c == '\'':
+		return l.quoted(byte(c))
+	case c == '-' || c == '+' || unicode.IsDigit(c):
+		return l.number()
+	case c == '_' || unicode.IsLetter(c):
+		for l.pos < len(l.source) {
+			r, n := utf8.DecodeRuneInString(l.source[l.pos:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+
+			l.pos += n
+		}
+
+		return token{kind: tokenIdentifier, text: l.source[start:l.pos], pos: start}, nil
+	}
+
+	l.pos += size
+
+	return token{}, l.errorf(start, "unexpected %q", c)
+}
+
+// quoted reads a string quoted with quote, in which a backslash escapes the next character
+func (l *lexer) quoted(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+
+	var b strings.Builder
+	for l.pos < len(l.source) {
+		c := l.source[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return token{kind: tokenString, text: b.String(), pos: start}, nil
+		case c == '\\' && l.pos+1 < len(l.source):
+			l.pos++
+			b.WriteByte(unescape(l.source[l.pos]))
+		default:
+			b.WriteByte(c)
+		}
+
+		l.pos++
+	}
+
+	return token{}, l.errorf(start, "string is never closed")
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	}
+
+	return c
+}
+
+// number reads an integer or a decimal number, with an optional sign and exponent
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	if c := l.source[l.pos]; c == '-' || c == '+' {
+		l.pos++
+	}
+
+	kind := tokenInt
+	for l.pos < len(l.source) {
+		c := l.source[l.pos]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' || c == 'e' || c == 'E':
+			kind = tokenFloat
+		case (c == '-' || c == '+') && (l.source[l.pos-1] == 'e' || l.source[l.pos-1] == 'E'):
+		default:
+			return l.numberToken(kind, start)
+		}
+
+		l.pos++
+	}
+
+	return l.numberToken(kind, start)
+}
+
+func (l *lexer) numberToken(kind tokenKind, start int) (token, error) {
+	text := l.source[start:l.pos]
+
+	var err error
+	if kind == tokenInt {
+		_, err = strconv.ParseInt(text, 10, 64)
+	} else {
+		_, err = strconv.ParseFloat(text, 64)
+	}
+
+	if err != nil {
+		return token{}, l.errorf(start, "%s is not a valid number", text)
+	}
+
+	return token{kind: kind, text: text, pos: start}, nil
+}
+
+// parser reads a faker expression, following
+//
+//	expression = "faker" { "." call }
+//	call       = identifier "(" [ argument { "," argument } ] ")"
+//	argument   = string | integer | number | true | false | nil | identifier
+//
+// where identifiers passed as arguments are read as strings, as in Locale(pt)
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+// parseExpression parses a faker expression, without calling any of its methods
+func parseExpression(source string) (*expression, error) {
+	p := &parser{lexer: &lexer{source: source}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	root := p.token
+	if root.kind != tokenIdentifier || root.text != FakerRoot {
+		return nil, p.lexer.errorf(root.pos, "expected %s, found %s", FakerRoot, describe(root))
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	e := &expression{source: source}
+	for p.token.kind != tokenEOF {
+		if _, err := p.expect(tokenDot); err != nil {
+			return nil, err
+		}
+
+		c, err := p.call()
+		if err != nil {
+			return nil, err
+		}
+
+		e.calls = append(e.calls, c)
+	}
+
+	if len(e.calls) == 0 {
+		return nil, p.lexer.errorf(p.token.pos, "expected a method call, as in %s.Person().Name()", FakerRoot)
+	}
+
+	return e, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+
+	p.token = t
+
+	return nil
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	t := p.token
+	if t.kind != kind {
+		return t, p.lexer.errorf(t.pos, "expected %s, found %s", kind, describe(t))
+	}
+
+	return t, p.advance()
+}
+
+func (p *parser) call() (call, error) {
+	name, err := p.expect(tokenIdentifier)
+	if err != nil {
+		return call{}, err
+	}
+
+	c := call{name: name.text, pos: name.pos}
+	if _, err = p.expect(tokenOpen); err != nil {
+		return c, err
+	}
+
+	if p.token.kind == tokenClose {
+		return c, p.advance()
+	}
+
+	for {
+		arg, aErr := p.argument()
+		if aErr != nil {
+			return c, aErr
+		}
+
+		c.args = append(c.args, arg)
+
+		if p.token.kind == tokenComma {
+			if err = p.advance(); err != nil {
+				return c, err
+			}
+
+			continue
+		}
+
+		if _, err = p.expect(tokenClose); err != nil {
+			return c, err
+		}
+
+		return c, nil
+	}
+}
+
+func (p *parser) argument() (literal, error) {
+	t := p.token
+	arg := literal{kind: t.kind, text: t.text, pos: t.pos}
+
+	switch t.kind {
+	case tokenString:
+		arg.value = t.text
+	case tokenInt:
+		arg.value, _ = strconv.ParseInt(t.text, 10, 64)
+	case tokenFloat:
+		arg.value, _ = strconv.ParseFloat(t.text, 64)
+	case tokenIdentifier:
+		switch t.text {
+		case "true", "false":
+			arg.value = t.text == "true"
+		case "nil":
+		default:
+			arg.kind, arg.value = tokenString, t.text
+		}
+	default:
+		return arg, p.lexer.errorf(t.pos, "expected an argument, found %s", describe(t))
+	}
+
+	return arg, p.advance()
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokenEOF:
+		return t.kind.String()
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+// step is a call of a compiled expression, whose method was looked up and whose arguments were
+// converted once. The methods of interfaces are only known when called, and are looked up on every call
+type step struct {
+	call   call
+	method reflect.Method
+	// pointer tells whether the method has a pointer receiver, while the receiver is a value
+	pointer bool
+	args    []reflect.Value
+}
+
+// compiled is an expression whose methods were looked up once, to be called for every value
+type compiled struct {
+	expression *expression
+	// locale returns the generators Locale calls start from, nil when the chain starts from faker
+	locale LocaleFactory
+	steps  []step
+}
+
+// compile looks up every method of the chain and converts their arguments. Locale calls
+// start from the generators of a locale, the one they are passed or fallback
+func (e *expression) compile(fallback string) (*compiled, error) {
+	c := &compiled{expression: e}
+	calls := e.calls
+	receiver := reflect.TypeOf(&faker.Faker{})
+
+	switch calls[0].name {
+	case ContactInfo:
+		return nil, e.errorf(calls[0].pos, "method %s is not supported", ContactInfo)
+	case LocaleMethod:
+		factory, err := e.locale(calls[0], fallback)
+		if err != nil {
+			return nil, err
+		}
+
+		c.locale = factory
+		receiver = reflect.TypeOf(factory(faker.New()))
+		calls = calls[1:]
+	}
+
+	for _, call := range calls {
+		s, err := e.compileCall(receiver, call)
+		if err != nil {
+			return nil, err
+		}
+
+		c.steps = append(c.steps, s)
+
+		// once a method returns an interface, the methods of the rest of the chain are looked up when called
+		receiver = nil
+		if s.method.Func.IsValid() {
+			receiver = s.method.Type.Out(0)
+		}
+	}
+
+	return c, nil
+}
+
+// compileCall looks up the method of c on receiver, nil when it is only known when called
+func (e *expression) compileCall(receiver reflect.Type, c call) (step, error) {
+	s := step{call: c}
+	if receiver == nil || receiver.Kind() == reflect.Interface {
+		return s, nil
+	}
+
+	method, ok := receiver.MethodByName(c.name)
+	if !ok && receiver.Kind() != reflect.Ptr {
+		method, ok = reflect.PointerTo(receiver).MethodByName(c.name)
+		s.pointer = true
+	}
+
+	if !ok || !isExported(c.name) {
+		return s, e.errorf(c.pos, "%s has no method %s", strings.TrimPrefix(receiver.String(), "*"), c.name)
+	}
+
+	if method.Type.NumOut() == 0 {
+		return s, e.errorf(c.pos, "%s does not return any value", c.name)
+	}
+
+	// the receiver is the first parameter of the method
+	params := make([]reflect.Type, 0, method.Type.NumIn()-1)
+	for i := 1; i < method.Type.NumIn(); i++ {
+		params = append(params, method.Type.In(i))
+	}
+
+	args, err := e.arguments(c, params, method.Type.IsVariadic())
+	if err != nil {
+		return s, err
+	}
+
+	s.method, s.args = method, args
+
+	return s, nil
+}
+
+// run calls every method of the chain, each on the result of the previous one, drawing values
+// from f, and returns the result of the last one
+func (c *compiled) run(f faker.Faker) (string, error) {
+	receiver := reflect.ValueOf(&f)
+	if c.locale != nil {
+		receiver = reflect.ValueOf(c.locale(f))
+	}
+
+	for _, s := range c.steps {
+		var err error
+		if receiver, err = c.expression.invoke(s, receiver); err != nil {
+			return "", err
+		}
+	}
+
+	if receiver.Kind() == reflect.String {
+		return receiver.String(), nil
+	}
+
+	return fmt.Sprintf("%v", receiver.Interface()), nil
+}
+
+func (e *expression) invoke(s step, receiver reflect.Value) (reflect.Value, error) {
+	if !s.method.Func.IsValid() {
+		return e.call(receiver, s.call)
+	}
+
+	if s.pointer {
+		p := reflect.New(receiver.Type())
+		p.Elem().Set(receiver)
+		receiver = p
+	}
+
+	in := make([]reflect.Value, 0, len(s.args)+1)
+	in = append(in, receiver)
+
+	return e.result(s.call, s.method.Func.Call(append(in, s.args...)))
+}
+
+// evaluate compiles the expression and runs it once
+func (e *expression) evaluate(f faker.Faker, fallback string) (string, error) {
+	c, err := e.compile(fallback)
+	if err != nil {
+		return "", err
+	}
+
+	return c.run(f)
+}
+
+// locale returns the factory of the locale a Locale call is passed, or of fallback
+func (e *expression) locale(c call, fallback string) (LocaleFactory, error) {
+	if len(c.args) > 1 || len(c.args) == 1 && c.args[0].kind != tokenString {
+		return nil, e.errorf(c.pos, "%s takes the code of a locale, as in %s(pt)", LocaleMethod, LocaleMethod)
+	}
+
+	code := fallback
+	if len(c.args) == 1 {
+		code = c.args[0].value.(string)
+	}
+
+	factory, err := localeFactory(code)
+	if err != nil {
+		return nil, e.errorf(c.pos, "%s", err)
+	}
+
+	return factory, nil
+}
+
+// call looks up the method of c on receiver and calls it, returning its first result
+func (e *expression) call(receiver reflect.Value, c call) (reflect.Value, error) {
+	method, ok := methodOf(receiver, c.name)
+	if !ok {
+		return reflect.Value{}, e.errorf(c.pos, "%s has no method %s", typeName(receiver), c.name)
+	}
+
+	mt := method.Type()
+
+	params := make([]reflect.Type, 0, mt.NumIn())
+	for i := 0; i < mt.NumIn(); i++ {
+		params = append(params, mt.In(i))
+	}
+
+	in, err := e.arguments(c, params, mt.IsVariadic())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return e.result(c, method.Call(in))
+}
+
+// arguments converts the arguments of c to the types of params, the last of which takes
+// any number of them when the method is variadic
+func (e *expression) arguments(c call, params []reflect.Type, variadic bool) ([]reflect.Value, error) {
+	if n := len(c.args); n < len(params)-1 || !variadic && n != len(params) {
+		return nil, e.errorf(c.pos, "%s takes %d arguments and got %d", c.name, len(params), n)
+	}
+
+	in := make([]reflect.Value, len(c.args))
+	for i, arg := range c.args {
+		t := params[min(i, len(params)-1)]
+		if variadic && i >= len(params)-1 {
+			t = t.Elem()
+		}
+
+		v, err := convertLiteral(arg, t)
+		if err != nil {
+			return nil, e.errorf(arg.pos, "argument %d of %s: %s", i+1, c.name, err)
+		}
+
+		in[i] = v
+	}
+
+	return in, nil
+}
+
+// result returns the first result of a call, failing when its last result is an error
+func (e *expression) result(c call, out []reflect.Value) (reflect.Value, error) {
+	if len(out) == 0 {
+		return reflect.Value{}, e.errorf(c.pos, "%s does not return any value", c.name)
+	}
+
+	if last := out[len(out)-1]; len(out) > 1 && last.Type().Implements(errorType) && !last.IsNil() {
+		return reflect.Value{}, e.errorf(c.pos, "%s: %s", c.name, last.Interface())
+	}
+
+	return out[0], nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// methodOf finds the method called name of v, whether it has a value or a pointer receiver
+func methodOf(v reflect.Value, name string) (reflect.Value, bool) {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if !v.IsValid() || !isExported(name) {
+		return reflect.Value{}, false
+	}
+
+	if m := v.MethodByName(name); m.IsValid() {
+		return m, true
+	}
+
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+
+		if m := p.MethodByName(name); m.IsValid() {
+			return m, true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func isExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+func typeName(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+
+	return strings.TrimPrefix(v.Type().String(), "*")
+}
+
+// convertLiteral converts arg to t, integers into any number, strings into times and durations
+func convertLiteral(arg literal, t reflect.Type) (reflect.Value, error) {
+	if arg.value == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return reflect.Zero(t), nil
+		}
+
+		return reflect.Value{}, fmt.Errorf("nil cannot be passed as %s", t)
+	}
+
+	v := reflect.ValueOf(arg.value)
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}) && arg.kind == tokenString:
+		for _, layout := range timeLayouts {
+			if parsed, err := time.Parse(layout, arg.text); err == nil {
+				return reflect.ValueOf(parsed), nil
+			}
+		}
+
+		return reflect.Value{}, fmt.Errorf("%q is not a time, as in %q", arg.text, dateTimeLayout)
+	case t == reflect.TypeOf(time.Duration(0)) && arg.kind == tokenString:
+		d, err := time.ParseDuration(arg.text)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%q is not a duration, as in \"1h30m\"", arg.text)
+		}
+
+		return reflect.ValueOf(d), nil
+	case t.Kind() == reflect.Interface && v.Type().Implements(t):
+		return v, nil
+	case t.Kind() == reflect.String && arg.kind == tokenString,
+		t.Kind() == reflect.Bool && v.Kind() == reflect.Bool,
+		isFloat(t.Kind()) && (arg.kind == tokenFloat || arg.kind == tokenInt):
+		return v.Convert(t), nil
+	case isInt(t.Kind()) && arg.kind == tokenInt:
+		n := v.Int()
+		if isUnsigned(t.Kind()) {
+			if n < 0 || reflect.Zero(t).OverflowUint(uint64(n)) {
+				return reflect.Value{}, fmt.Errorf("%d does not fit in %s", n, t)
+			}
+		} else if reflect.Zero(t).OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("%d does not fit in %s", n, t)
+		}
+
+		return v.Convert(t), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("%s %s cannot be passed as %s", arg.kind, arg.text, t)
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uint64
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func (e *expression) errorf(pos int, format string, args ...interface{}) error {
+	return (&lexer{source: e.source}).errorf(pos, format, args...)
+}
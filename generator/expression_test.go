@@ -0,0 +1,241 @@
+package generator
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+// literals has a method for every kind of argument faker expressions can be passed
+type literals struct{}
+
+func (literals) Ratio(r float64) float64                 { return r }
+func (literals) Flag(b bool) bool                        { return b }
+func (literals) Byte(b uint8) uint8                      { return b }
+func (literals) Join(sep string, parts ...string) string { return strings.Join(parts, sep) }
+func (literals) Wait(d time.Duration) time.Duration      { return d }
+func (literals) Year(t time.Time) int                    { return t.Year() }
+func (literals) Describe(v interface{}) string           { return reflect.TypeOf(v).String() }
+func (literals) Fail() (string, error)                   { return "", errors.New("failed") }
+func (literals) Self() literals                          { return literals{} }
+func (literals) Text(s string, n int) string             { return strings.Repeat(s, n) }
+func (*literals) Pointer(names []string) int             { return len(names) }
+
+func Test_parseExpression(t *testing.T) {
+	t.Parallel()
+
+	e, err := parseExpression(`faker . Lorem ( ) .Join(", ", 'it\'s', "say \"hi\"", -1.5e3, 42, true, nil, pt)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []call{
+		{name: "Lorem", pos: 8},
+		{
+			name: "Join",
+			pos:  19,
+			args: []literal{
+				{kind: tokenString, text: ", ", value: ", ", pos: 24},
+				{kind: tokenString, text: "it's", value: "it's", pos: 30},
+				{kind: tokenString, text: `say "hi"`, value: `say "hi"`, pos: 39},
+				{kind: tokenFloat, text: "-1.5e3", value: -1500.0, pos: 53},
+				{kind: tokenInt, text: "42", value: int64(42), pos: 61},
+				{kind: tokenIdentifier, text: "true", value: true, pos: 65},
+				{kind: tokenIdentifier, text: "nil", pos: 71},
+				{kind: tokenString, text: "pt", value: "pt", pos: 76},
+			},
+		},
+	}
+	if !reflect.DeepEqual(e.calls, want) {
+		t.Errorf("parseExpression() got = %+v, want %+v", e.calls, want)
+	}
+}
+
+func Test_parseExpression_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"faker", `faker expression "faker", at position 6: expected a method call, as in faker.Person().Name()`},
+		{"faker.Bla", `faker expression "faker.Bla", at position 10: expected (, found end of expression`},
+		{"faker.Bla(", `faker expression "faker.Bla(", at position 11: expected an argument, found end of expression`},
+		{"faker.Bla().Fuu", `faker expression "faker.Bla().Fuu", at position 16: expected (, found end of expression`},
+		{"faker.Lorem().Sentence(5", `faker expression "faker.Lorem().Sentence(5", at position 25: expected ), found end of expression`},
+		{"faker.Lorem().Sentence(5 6)", `faker expression "faker.Lorem().Sentence(5 6)", at position 26: expected ), found "6"`},
+		{"faker.Asciify('***)", `faker expression "faker.Asciify('***)", at position 15: string is never closed`},
+		{"faker.Float(1.2.3)", `faker expression "faker.Float(1.2.3)", at position 13: 1.2.3 is not a valid number`},
+		{"faker.Lorem()Word()", `faker expression "faker.Lorem()Word()", at position 14: expected ., found "Word"`},
+		{"faker.Lorem(;)", `faker expression "faker.Lorem(;)", at position 13: unexpected ';'`},
+		{"fakér.Lorem()", `faker expression "fakér.Lorem()", at position 1: expected faker, found "fakér"`},
+		{"faker.Ação(;)", `faker expression "faker.Ação(;)", at position 12: unexpected ';'`},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.expression, func(t *testing.T) {
+				_, err := parseExpression(tt.expression)
+				if err == nil || err.Error() != tt.want {
+					t.Errorf("parseExpression() error = %v, want %v", err, tt.want)
+				}
+
+				var eErr *ExpressionError
+				if !errors.As(err, &eErr) {
+					t.Errorf("parseExpression() error = %T, want *ExpressionError", err)
+				}
+			},
+		)
+	}
+}
+
+func Test_expression_call(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		call    string
+		want    interface{}
+		wantErr string
+	}{
+		{"float", "Ratio(0.25)", 0.25, ""},
+		{"integer as float", "Ratio(2)", 2.0, ""},
+		{"bool", "Flag(false)", false, ""},
+		{"small integer", "Byte(255)", uint8(255), ""},
+		{"variadic", "Join('-', 'a', \"b\", c)", "a-b-c", ""},
+		{"variadic without values", "Join('-')", "", ""},
+		{"duration", "Wait('1h30m')", 90 * time.Minute, ""},
+		{"date", "Year('1999-12-31')", 1999, ""},
+		{"datetime", "Year('2001-02-03 04:05:06')", 2001, ""},
+		{"interface", "Describe(1.5)", "float64", ""},
+		{"nil slice on a pointer receiver", "Pointer(nil)", 0, ""},
+		{"spaces and commas", "Text('a, b ', 2)", "a, b a, b ", ""},
+		{"overflow", "Byte(256)", nil, "at position 19: argument 1 of Byte: 256 does not fit in uint8"},
+		{"negative unsigned", "Byte(-1)", nil, "at position 19: argument 1 of Byte: -1 does not fit in uint8"},
+		{"float as integer", "Byte(1.5)", nil, "at position 19: argument 1 of Byte: number 1.5 cannot be passed as uint8"},
+		{"string as bool", "Flag('true')", nil, "at position 19: argument 1 of Flag: string true cannot be passed as bool"},
+		{"invalid date", "Year('31/12/1999')", nil, `argument 1 of Year: "31/12/1999" is not a time, as in "2006-01-02 15:04:05"`},
+		{"invalid duration", "Wait('soon')", nil, `"soon" is not a duration`},
+		{"nil bool", "Flag(nil)", nil, "nil cannot be passed as bool"},
+		{"too few", "Text('a')", nil, "at position 14: Text takes 2 arguments and got 1"},
+		{"too many", "Flag(true, false)", nil, "Flag takes 1 arguments and got 2"},
+		{"unexported", "self()", nil, "generator.literals has no method self"},
+		{"error returned", "Fail()", nil, "at position 14: Fail: failed"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				e, err := parseExpression("faker.Self()." + tt.call)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := e.call(reflect.ValueOf(literals{}), e.calls[1])
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("call() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("call() error = %v", err)
+				}
+
+				if !reflect.DeepEqual(got.Interface(), tt.want) {
+					t.Errorf("call() got = %#v, want %#v", got.Interface(), tt.want)
+				}
+			},
+		)
+	}
+}
+
+func Test_expression_evaluate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		want    *regexp.Regexp
+		wantErr string
+	}{
+		{"two levels", "faker.Lorem().Sentence(5)", regexp.MustCompile(`^(\w+ ){4}\w+\.$`), ""},
+		{"string with spaces", `faker.Numerify("## ##, ##")`, regexp.MustCompile(`^\d\d \d\d, \d\d$`), ""},
+		{
+			"three levels with times",
+			"faker.Time().TimeBetween('2020-01-01', '2020-12-31 23:59:59').Format('2006/01')",
+			regexp.MustCompile(`^2020/(0[1-9]|1[0-2])$`),
+			"",
+		},
+		{"locale", "faker.Locale(pt).PostalCode()", regexp.MustCompile(`^\d{4}-\d{3}$`), ""},
+		{"missing method", "faker.Yada()", nil, "at position 7: faker.Faker has no method Yada"},
+		{"missing method deep down", "faker.Time().Time('2020-01-01').Yada()", nil, "at position 33: time.Time has no method Yada"},
+		{"contact info", "faker.ContactInfo()", nil, "method ContactInfo is not supported"},
+		{"locale argument", "faker.Locale(1).NIF()", nil, "at position 7: Locale takes the code of a locale"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				e, err := parseExpression(tt.request)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := e.evaluate(faker.NewWithSeedInt64(42), "")
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("evaluate() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+
+				if err != nil || !tt.want.MatchString(got) {
+					t.Errorf("evaluate() got = %v, %v, want %v", got, err, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func Test_expression_compile(t *testing.T) {
+	t.Parallel()
+
+	e, err := parseExpression("faker.Self().Pointer(nil)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// methods of pointer receivers are found on values, and their arguments converted once
+	s, err := e.compileCall(reflect.TypeOf(literals{}), e.calls[1])
+	if err != nil || !s.pointer || len(s.args) != 1 {
+		t.Fatalf("compileCall() got = %+v, %v", s, err)
+	}
+
+	got, err := e.invoke(s, reflect.ValueOf(literals{}))
+	if err != nil || got.Interface() != 0 {
+		t.Errorf("invoke() got = %v, %v", got, err)
+	}
+
+	// the arguments of every call of the chain are checked when it is compiled, not when it is run
+	e, _ = parseExpression("faker.Time().Time('2020-01-01').AddDate(1, 'a', 0)")
+	if _, err = e.compile(""); err == nil || !strings.Contains(err.Error(), "at position 44: argument 2 of AddDate") {
+		t.Errorf("compile() error = %v", err)
+	}
+
+	// a compiled expression draws its values from the faker it is run with
+	e, _ = parseExpression("faker.Locale(pt).NIF()")
+	c, err := e.compile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := c.run(faker.NewWithSeedInt64(1))
+	again, _ := c.run(faker.NewWithSeedInt64(1))
+	if first != again {
+		t.Errorf("run() got = %v and %v with the same seed", first, again)
+	}
+}
@@ -1,9 +1,10 @@
 package generator
 
 import (
-	"errors"
-	"fmt"
-	"reflect"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand/v2"
 	"strconv"
 	"strings"
 
@@ -12,172 +13,210 @@ import (
 
 type Service interface {
 	ReplaceStringWithFakerWhenRequested(request string) (string, error)
+	// ReplaceStringWithFakerFor works as ReplaceStringWithFakerWhenRequested, but seeds faker
+	// from original, so the same original value always gets the same fake value
+	ReplaceStringWithFakerFor(request string, original []byte) (string, error)
+	// ForColumn returns the Service the fake values of column are generated with. Seeded services
+	// return one seeded for that column alone, so its values do not depend on any other column
+	ForColumn(table, column string) Service
+	// Transform compiles a rule starting with TransformPrefix, whose random values are drawn as
+	// the fake values of this Service are, from the original value when it is deterministic
+	Transform(rule string) (*Transform, error)
+	// Compile parses a faker expression and looks up its methods once, into a FakerFunc
+	// generating a value on every call. Other requests are generated as they are
+	Compile(request string) (FakerFunc, error)
+	// Template compiles a rule starting with TemplatePrefix, whose faker and locale functions draw
+	// their values as the fake values of this Service are, from the original value when it is deterministic
+	Template(rule string) (*Template, error)
 }
 
+// FakerFunc generates a fake value, from original when its Service is deterministic
+type FakerFunc func(original []byte) (string, error)
+
 type service struct {
-	faker faker.Faker
+	faker  faker.Faker
+	key    []byte
+	seed   *int64
+	locale string
+}
+
+// ServiceOption configures a Service when it is created
+type ServiceOption func(s *service)
+
+// WithLocale sets the locale faker.Locale() generates its values for, see ValidateLocale
+func WithLocale(code string) ServiceOption {
+	return func(s *service) {
+		s.locale = code
+	}
 }
 
 const (
-	FakerShortLen = 2
-	ContactInfo   = "ContactInfo"
+	ContactInfo = "ContactInfo"
 )
 
-func NewService() Service {
-	return &service{
+func NewService(options ...ServiceOption) Service {
+	return newService(&service{
 		faker: faker.New(),
-	}
+	}, options)
 }
 
-func (s service) ReplaceStringWithFakerWhenRequested(request string) (string, error) {
-	if len(request) < 5 || request[0:5] != "faker" {
-		return request, nil
-	}
-
-	a := strings.Split(request, ".")
-	if len(a) < 2 {
-		return request,
-			errors.New("requires more arguments to get a faker func")
-	}
+// NewDeterministicService returns a Service whose fake values for an original value are
+// seeded from an HMAC of that value with key, changing the key changes every fake value
+func NewDeterministicService(key []byte, options ...ServiceOption) Service {
+	return newService(&service{
+		faker: faker.New(),
+		key:   key,
+	}, options)
+}
 
-	var res []reflect.Value
-	var err error
+// NewSeededService returns a Service whose columns are seeded from seed,
+// so runs over the same data with the same seed generate the same values
+func NewSeededService(seed int64, options ...ServiceOption) Service {
+	return newService(&service{
+		faker: faker.NewWithSeedInt64(seed),
+		seed:  &seed,
+	}, options)
+}
 
-	method := strings.Split(a[1], "(")[0]
-	if method == ContactInfo {
-		return request, errors.New("method ContactInfo is not supported")
+func newService(s *service, options []ServiceOption) Service {
+	for _, option := range options {
+		option(s)
 	}
 
-	if len(a) == FakerShortLen {
-		argsString := strings.Split(a[1], "(")
-
-		if len(argsString) < 2 {
-			return request,
-				errors.New("requires more arguments to get a faker func")
-		}
-
-		args, dErr := getMethodArguments(
-			&s.faker,
-			argsString[0],
-			strings.Replace(argsString[1], ")", "", -1),
-		)
+	return s
+}
 
-		if dErr != nil {
-			return "", dErr
-		}
+func (s service) ForColumn(table, column string) Service {
+	if s.seed == nil {
+		return &s
+	}
 
-		if res, dErr = callMethod(&s.faker, method, args); dErr != nil {
-			return "", dErr
-		}
+	h := sha256.New()
+	_ = binary.Write(h, binary.BigEndian, *s.seed)
+	h.Write([]byte(table))
+	h.Write([]byte{0})
+	h.Write([]byte(column))
 
-		return stringify(res[0]), nil
+	return &service{
+		faker:  newFakerFromSum(h.Sum(nil)),
+		key:    s.key,
+		seed:   s.seed,
+		locale: s.locale,
 	}
+}
 
-	if res, err = callMethod(&s.faker, method, nil); err != nil {
-		return "", err
+func (s service) ReplaceStringWithFakerWhenRequested(request string) (string, error) {
+	generate, err := compileFaker(request, s.locale)
+	if err != nil {
+		return request, err
 	}
 
-	argsString := strings.Split(a[2], "(")
+	return generate(s.faker)
+}
 
-	if len(argsString) < 2 {
-		return request,
-			errors.New("requires more arguments to get a faker func")
+func (s service) ReplaceStringWithFakerFor(request string, original []byte) (string, error) {
+	generate, err := compileFaker(request, s.locale)
+	if err != nil {
+		return request, err
 	}
 
-	obj := reflect.Indirect(res[0]).Interface()
+	return generate(s.fakerFor(original))
+}
 
-	args, err := getMethodArguments(
-		obj,
-		argsString[0],
-		strings.Replace(argsString[1], ")", "", -1),
-	)
+func (s service) Compile(request string) (FakerFunc, error) {
+	generate, err := compileFaker(request, s.locale)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	// faker as a max depth of 2, here should be last
-	// @todo this analysis should be dynamic
-	if res, err = callMethod(obj, argsString[0], args); err != nil {
-		return "", err
+
+	if s.key != nil {
+		return func(original []byte) (string, error) {
+			return generate(s.fakerFor(original))
+		}, nil
 	}
 
-	return stringify(res[0]), nil
-}
+	f := s.faker
 
-func getMethodArguments(
-	entry interface{},
-	name string,
-	argsString string,
-) ([]reflect.Value, error) {
-	_, exists := reflect.TypeOf(entry).MethodByName(name)
+	return func([]byte) (string, error) {
+		return generate(f)
+	}, nil
+}
 
-	if !exists {
-		return nil,
-			errors.New("could not find faker func")
-	}
+// fakerFor seeds faker from an HMAC of original with the key of the service
+func (s service) fakerFor(original []byte) faker.Faker {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(original)
 
-	method := reflect.ValueOf(entry).
-		MethodByName(name)
+	return newFakerFromSum(mac.Sum(nil))
+}
 
-	in := make([]reflect.Value, method.Type().NumIn())
+func (s service) Transform(rule string) (*Transform, error) {
+	t, err := ParseTransform(rule)
+	if err != nil {
+		return nil, err
+	}
 
-	// we need to skip if we have an empty string, else it fails the lower validation
-	// and function goes kaboom
-	if argsString == "" {
-		return in, nil
+	if s.key != nil {
+		t.faker = s.fakerFor
+	} else {
+		t.faker = func([]byte) faker.Faker {
+			return s.faker
+		}
 	}
 
-	args := strings.Split(argsString, ",")
+	return t, nil
+}
 
-	if len(args) != method.Type().NumIn() {
-		return nil, fmt.Errorf(
-			"number of arguments to call %s is %d and we got %d",
-			name,
-			method.Type().NumIn(),
-			len(args),
-		)
+func (s service) Template(rule string) (*Template, error) {
+	t, err := parseTemplate(rule, s.locale)
+	if err != nil {
+		return nil, err
 	}
 
-	for i := 0; i < method.Type().NumIn(); i++ {
-		t := method.Type().In(i)
-		in[i] = reflect.ValueOf(convert(t, clearString(args[i]))).Convert(t)
+	if s.key != nil {
+		t.faker = s.fakerFor
+	} else {
+		t.faker = func([]byte) faker.Faker {
+			return s.faker
+		}
 	}
 
-	return in, nil
+	return t, nil
 }
 
-func callMethod(
-	entry interface{},
-	name string,
-	objects []reflect.Value,
-) ([]reflect.Value, error) {
-	_, exists := reflect.TypeOf(entry).MethodByName(name)
-
-	if !exists {
-		return nil,
-			errors.New("could not find faker func")
+// RetryOriginal returns what the fake value of original is seeded from on its attempt-th retry, so that
+// deterministic services generate another value every time, and the same ones on every run
+func RetryOriginal(original []byte, attempt int) []byte {
+	if attempt == 0 {
+		return original
 	}
 
-	return reflect.ValueOf(entry).
-		MethodByName(name).
-		Call(objects), nil
+	return strconv.AppendInt(append(append([]byte{}, original...), 0), int64(attempt), 10)
 }
 
-func clearString(str string) string {
-	s := strings.Replace(str, "\"", "", -1)
-	return strings.Replace(s, " ", "", -1)
+// newFakerFromSum seeds faker from the first 16 bytes of a hash sum
+func newFakerFromSum(sum []byte) faker.Faker {
+	return faker.NewWithSeed(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
 }
 
-func stringify(v interface{}) string {
-	return fmt.Sprintf("%v", v)
-}
+// compileFaker compiles request when it is a faker expression, drawing its values from the
+// faker it is called with. Any other request is generated as it is
+func compileFaker(request, locale string) (func(f faker.Faker) (string, error), error) {
+	if !strings.HasPrefix(request, FakerRoot) {
+		return func(faker.Faker) (string, error) {
+			return request, nil
+		}, nil
+	}
 
-func convert(t reflect.Type, s string) interface{} {
-	// nolint
-	switch t.Kind() {
-	case reflect.Int, reflect.Int64:
-		v, _ := strconv.Atoi(s)
-		return v
-	default:
-		return s
+	e, err := parseExpression(request)
+	if err != nil {
+		return nil, err
 	}
+
+	c, err := e.compile(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.run, nil
 }
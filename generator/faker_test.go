@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -212,3 +213,70 @@ func Test_service_FakerError(t *testing.T) {
 		)
 	}
 }
+
+func Test_service_ReplaceStringWithFakerFor(t *testing.T) {
+	t.Parallel()
+
+	s := NewDeterministicService([]byte("secret"))
+
+	first, err := s.ReplaceStringWithFakerFor("faker.Internet().Email()", []byte("jane@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same original value gets the same fake value, even from another service with the same key
+	same, _ := NewDeterministicService([]byte("secret")).
+		ReplaceStringWithFakerFor("faker.Internet().Email()", []byte("jane@example.com"))
+	if first != same {
+		t.Errorf("ReplaceStringWithFakerFor() got = %v and %v for the same value", first, same)
+	}
+
+	other, _ := s.ReplaceStringWithFakerFor("faker.Internet().Email()", []byte("john@example.com"))
+	if first == other {
+		t.Errorf("ReplaceStringWithFakerFor() got = %v for different values", other)
+	}
+
+	rekeyed, _ := NewDeterministicService([]byte("another secret")).
+		ReplaceStringWithFakerFor("faker.Internet().Email()", []byte("jane@example.com"))
+	if first == rekeyed {
+		t.Errorf("ReplaceStringWithFakerFor() got = %v with a different key", rekeyed)
+	}
+
+	if _, err = s.ReplaceStringWithFakerFor("faker.Bla()", []byte("jane@example.com")); err == nil {
+		t.Errorf("ReplaceStringWithFakerFor() should have errored")
+	}
+}
+
+func Test_service_ForColumn(t *testing.T) {
+	t.Parallel()
+
+	generate := func(s Service, n int) []string {
+		values := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := s.ReplaceStringWithFakerWhenRequested("faker.Person().Name()")
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v)
+		}
+
+		return values
+	}
+
+	first := generate(NewSeededService(42).ForColumn("users", "name"), 5)
+
+	// another run with the same seed generates the same values, whatever the other columns generated before
+	s := NewSeededService(42)
+	generate(s.ForColumn("users", "email"), 3)
+	if again := generate(s.ForColumn("users", "name"), 5); !reflect.DeepEqual(first, again) {
+		t.Errorf("ForColumn() got = %v, want %v", again, first)
+	}
+
+	if other := generate(NewSeededService(43).ForColumn("users", "name"), 5); reflect.DeepEqual(first, other) {
+		t.Errorf("ForColumn() got = %v with a different seed", other)
+	}
+
+	if other := generate(NewSeededService(42).ForColumn("customers", "name"), 5); reflect.DeepEqual(first, other) {
+		t.Errorf("ForColumn() got = %v for another table", other)
+	}
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+// LocaleMethod is the faker method the generators of a locale are reached with, as in
+// faker.Locale().Name() for the configured locale or faker.Locale(pt).NIF() for a given one
+const LocaleMethod = "Locale"
+
+// LocaleGenerator generates data that passes the validations of a country. Every locale implements
+// these, and may add its own, such as the NIF and NISS of Portugal, which rules reach by name
+type LocaleGenerator interface {
+	FirstName() string
+	LastName() string
+	Name() string
+	Address() string
+	PostalCode() string
+	City() string
+	Phone() string
+	IBAN() string
+	// TaxID is the number people are identified with by the tax authority, the NIF in Portugal
+	TaxID() string
+}
+
+// LocaleFactory returns the generator of a locale drawing its values from f
+type LocaleFactory func(f faker.Faker) LocaleGenerator
+
+var locales = map[string]LocaleFactory{
+	"pt": newPortuguese,
+}
+
+// RegisterLocale makes the generators of a locale available under code, replacing any registered before
+func RegisterLocale(code string, factory LocaleFactory) {
+	locales[normalizeLocale(code)] = factory
+}
+
+// ValidateLocale fails for any locale that is not registered, none standing for no locale
+func ValidateLocale(code string) error {
+	if code == "" {
+		return nil
+	}
+
+	if _, ok := locales[normalizeLocale(code)]; !ok {
+		return fmt.Errorf("unsupported locale %q, use one of %s", code, strings.Join(localeCodes(), ", "))
+	}
+
+	return nil
+}
+
+// localeFactory returns the factory of the generators of locale
+func localeFactory(code string) (LocaleFactory, error) {
+	if code == "" {
+		return nil, fmt.Errorf("no locale is configured, set one or pass it as in %s(pt)", LocaleMethod)
+	}
+
+	if err := ValidateLocale(code); err != nil {
+		return nil, err
+	}
+
+	return locales[normalizeLocale(code)], nil
+}
+
+// normalizeLocale lets pt-BR, pt_BR and PT_br stand for the same locale
+func normalizeLocale(code string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(code)), "-", "_")
+}
+
+func localeCodes() []string {
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+
+	return codes
+}
+
+// ibanCheckDigits computes the check digits of the IBAN of country with bban, following ISO 13616:
+// letters count as two digits, from A as 10, and the country code goes after the BBAN
+func ibanCheckDigits(country, bban string) string {
+	var digits strings.Builder
+	for _, c := range strings.ToUpper(bban + country + "00") {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(fmt.Sprint(c - 'A' + 10))
+			continue
+		}
+
+		digits.WriteRune(c)
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+
+	return fmt.Sprintf("%02d", 98-new(big.Int).Mod(n, big.NewInt(97)).Int64())
+}
+
+// digits draws n random digits
+func digits(f faker.Faker, n int) []int {
+	d := make([]int, n)
+	for i := range d {
+		d[i] = f.RandomDigit()
+	}
+
+	return d
+}
+
+func digitString(d []int) string {
+	var b strings.Builder
+	for _, n := range d {
+		b.WriteByte(byte('0' + n))
+	}
+
+	return b.String()
+}
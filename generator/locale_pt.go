@@ -0,0 +1,203 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+// portuguese generates data that passes the validations of Portugal, such as
+// the check digits of the NIF, NISS and NIB the IBAN is made of
+type portuguese struct {
+	f faker.Faker
+}
+
+// ptCity is a city along with the range of the first four digits of its postal codes
+type ptCity struct {
+	name     string
+	from, to int
+}
+
+var (
+	ptFirstNames = []string{
+		"Afonso", "Ana", "André", "António", "Beatriz", "Bruno", "Carla", "Carolina", "Catarina", "Cláudia",
+		"Daniel", "Diogo", "Duarte", "Filipa", "Francisca", "Francisco", "Gonçalo", "Guilherme", "Helena",
+		"Inês", "Joana", "João", "Jorge", "José", "Leonor", "Luís", "Manuel", "Margarida", "Maria", "Mariana",
+		"Marta", "Matilde", "Miguel", "Nuno", "Patrícia", "Paulo", "Pedro", "Raquel", "Ricardo", "Rita",
+		"Rodrigo", "Rui", "Sara", "Sofia", "Teresa", "Tiago", "Tomás", "Vasco",
+	}
+
+	ptLastNames = []string{
+		"Almeida", "Alves", "Antunes", "Barbosa", "Batista", "Cardoso", "Carvalho", "Correia", "Costa", "Cunha",
+		"Dias", "Fernandes", "Ferreira", "Fonseca", "Gomes", "Gonçalves", "Lopes", "Marques", "Martins",
+		"Matos", "Mendes", "Monteiro", "Moreira", "Nunes", "Oliveira", "Pereira", "Pinheiro", "Pinto", "Ramos",
+		"Reis", "Ribeiro", "Rocha", "Rodrigues", "Santos", "Silva", "Sousa", "Teixeira", "Vieira",
+	}
+
+	ptStreetTypes = []string{"Rua", "Rua", "Rua", "Avenida", "Travessa", "Largo", "Praça", "Estrada"}
+
+	ptStreetNames = []string{
+		"da Liberdade", "de Santa Catarina", "25 de Abril", "dos Combatentes", "da República", "do Comércio",
+		"Almirante Reis", "de São Bento", "da Boavista", "Dom João II", "Luís de Camões", "Vasco da Gama",
+		"das Flores", "do Carmo", "da Igreja", "da Estação", "Dr. Manuel de Arriaga", "1º de Maio",
+		"Infante Dom Henrique", "Marquês de Pombal", "da Misericórdia", "do Mercado",
+	}
+
+	ptCities = []ptCity{
+		{"Lisboa", 1000, 1990},
+		{"Porto", 4000, 4369},
+		{"Vila Nova de Gaia", 4400, 4439},
+		{"Braga", 4700, 4719},
+		{"Guimarães", 4800, 4839},
+		{"Aveiro", 3800, 3814},
+		{"Coimbra", 3000, 3049},
+		{"Viseu", 3500, 3519},
+		{"Leiria", 2400, 2419},
+		{"Setúbal", 2900, 2914},
+		{"Évora", 7000, 7005},
+		{"Faro", 8000, 8009},
+		{"Funchal", 9000, 9064},
+		{"Ponta Delgada", 9500, 9504},
+	}
+
+	// ptBanks are the codes of banks, which IBANs and NIBs start with
+	ptBanks = []string{"0007", "0010", "0018", "0023", "0033", "0035", "0036", "0045", "0079", "0269"}
+
+	ptMobilePrefixes   = []string{"91", "92", "93", "96"}
+	ptLandlinePrefixes = []string{"21", "22", "231", "232", "234", "239", "244", "253", "265", "266", "289", "291"}
+)
+
+func newPortuguese(f faker.Faker) LocaleGenerator {
+	return &portuguese{f: f}
+}
+
+func (p *portuguese) FirstName() string {
+	return p.f.RandomStringElement(ptFirstNames)
+}
+
+func (p *portuguese) LastName() string {
+	return p.f.RandomStringElement(ptLastNames)
+}
+
+// Name is a first name followed by two last names, the mother's and then the father's
+func (p *portuguese) Name() string {
+	return fmt.Sprintf("%s %s %s", p.FirstName(), p.LastName(), p.LastName())
+}
+
+// StreetAddress is a street along with a door number, as in Rua da Liberdade, 25
+func (p *portuguese) StreetAddress() string {
+	return fmt.Sprintf(
+		"%s %s, %d",
+		p.f.RandomStringElement(ptStreetTypes),
+		p.f.RandomStringElement(ptStreetNames),
+		p.f.IntBetween(1, 250),
+	)
+}
+
+// Address is a street address followed by the postal code of its city, as in
+// Rua da Liberdade, 25, 1250-096 Lisboa
+func (p *portuguese) Address() string {
+	city := p.city()
+	return fmt.Sprintf("%s, %s %s", p.StreetAddress(), p.postalCodeOf(city), city.name)
+}
+
+// PostalCode is made of four digits, a dash and three digits, as in 1250-096
+func (p *portuguese) PostalCode() string {
+	return p.postalCodeOf(p.city())
+}
+
+func (p *portuguese) City() string {
+	return p.city().name
+}
+
+func (p *portuguese) city() ptCity {
+	return ptCities[p.f.IntBetween(0, len(ptCities)-1)]
+}
+
+func (p *portuguese) postalCodeOf(city ptCity) string {
+	return fmt.Sprintf("%04d-%03d", p.f.IntBetween(city.from, city.to), p.f.IntBetween(0, 999))
+}
+
+// Phone is a mobile or a landline number, with the +351 country code
+func (p *portuguese) Phone() string {
+	if p.f.IntBetween(0, 1) == 0 {
+		return p.MobilePhone()
+	}
+
+	return p.phoneWithPrefix(p.f.RandomStringElement(ptLandlinePrefixes))
+}
+
+// MobilePhone is a mobile number, with the +351 country code, as in +351 912 345 678
+func (p *portuguese) MobilePhone() string {
+	return p.phoneWithPrefix(p.f.RandomStringElement(ptMobilePrefixes))
+}
+
+func (p *portuguese) phoneWithPrefix(prefix string) string {
+	number := prefix + digitString(digits(p.f, 9-len(prefix)))
+	return fmt.Sprintf("+351 %s %s %s", number[:3], number[3:6], number[6:])
+}
+
+// NIF is the tax number of a person, nine digits starting with 1, 2 or 3 and ending with a check digit
+func (p *portuguese) NIF() string {
+	d := append([]int{p.f.IntBetween(1, 3)}, digits(p.f, 7)...)
+	return digitString(append(d, nifCheckDigit(d)))
+}
+
+func (p *portuguese) TaxID() string {
+	return p.NIF()
+}
+
+// NISS is the social security number of a person, eleven digits starting with 1 and ending with a check digit
+func (p *portuguese) NISS() string {
+	d := append([]int{1}, digits(p.f, 9)...)
+	return digitString(append(d, nissCheckDigit(d)))
+}
+
+// NIB is the bank account number, the bank, branch and account followed by two check digits
+func (p *portuguese) NIB() string {
+	account := p.f.RandomStringElement(ptBanks) + digitString(digits(p.f, 15))
+	return account + nibCheckDigits(account)
+}
+
+// IBAN is the NIB preceded by PT and the check digits of the IBAN, which are always 50
+func (p *portuguese) IBAN() string {
+	nib := p.NIB()
+	return "PT" + ibanCheckDigits("PT", nib) + nib
+}
+
+// nifCheckDigit weighs the eight digits of a NIF from 9 down to 2, the check digit
+// completing their sum to a multiple of 11, 0 when it would be 10 or 11
+func nifCheckDigit(d []int) int {
+	sum := 0
+	for i, n := range d {
+		sum += n * (9 - i)
+	}
+
+	if check := 11 - sum%11; check < 10 {
+		return check
+	}
+
+	return 0
+}
+
+// nissCheckDigit weighs the ten digits of a NISS by the primes from 29 down to 2
+func nissCheckDigit(d []int) int {
+	weights := []int{29, 23, 19, 17, 13, 11, 7, 5, 3, 2}
+
+	sum := 0
+	for i, n := range d {
+		sum += n * weights[i]
+	}
+
+	return 9 - sum%10
+}
+
+// nibCheckDigits are 98 minus the remainder of the nineteen digits of the account, times 100, by 97
+func nibCheckDigits(account string) string {
+	rest := 0
+	for _, c := range account + "00" {
+		rest = (rest*10 + int(c-'0')) % 97
+	}
+
+	return fmt.Sprintf("%02d", 98-rest)
+}
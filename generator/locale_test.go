@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+// validIBAN checks the IBAN as banks do, moving its first four characters to the end
+// and reading letters as two digits, which leaves a remainder of 1 by 97
+func validIBAN(iban string) bool {
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(big.NewInt(int64(c - 'A' + 10)).String())
+			continue
+		}
+
+		digits.WriteRune(c)
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func validNIF(nif string) bool {
+	sum := 0
+	for i, c := range nif[:8] {
+		sum += int(c-'0') * (9 - i)
+	}
+
+	check := 0
+	if r := sum % 11; r >= 2 {
+		check = 11 - r
+	}
+
+	return int(nif[8]-'0') == check
+}
+
+func validNISS(niss string) bool {
+	weights := []int{29, 23, 19, 17, 13, 11, 7, 5, 3, 2}
+
+	sum := 0
+	for i, w := range weights {
+		sum += int(niss[i]-'0') * w
+	}
+
+	return int(niss[10]-'0') == 9-sum%10
+}
+
+func Test_portuguese(t *testing.T) {
+	t.Parallel()
+
+	pt := newPortuguese(faker.NewWithSeedInt64(42)).(*portuguese)
+
+	tests := []struct {
+		name     string
+		generate func() string
+		format   *regexp.Regexp
+		valid    func(string) bool
+	}{
+		{"NIF", pt.NIF, regexp.MustCompile(`^[123]\d{8}$`), validNIF},
+		{"TaxID", pt.TaxID, regexp.MustCompile(`^[123]\d{8}$`), validNIF},
+		{"NISS", pt.NISS, regexp.MustCompile(`^1\d{10}$`), validNISS},
+		{"IBAN", pt.IBAN, regexp.MustCompile(`^PT50\d{21}$`), validIBAN},
+		{"Phone", pt.Phone, regexp.MustCompile(`^\+351 [29]\d{2} \d{3} \d{3}$`), nil},
+		{"MobilePhone", pt.MobilePhone, regexp.MustCompile(`^\+351 9[1236]\d \d{3} \d{3}$`), nil},
+		{"PostalCode", pt.PostalCode, regexp.MustCompile(`^[1-9]\d{3}-\d{3}$`), nil},
+		{"Name", pt.Name, regexp.MustCompile(`^\pL+ \pL+ \pL+$`), nil},
+		{"Address", pt.Address, regexp.MustCompile(`^\pL+ .+, \d+, [1-9]\d{3}-\d{3} \pL[\pL ]+$`), nil},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for i := 0; i < 200; i++ {
+					got := tt.generate()
+					if !tt.format.MatchString(got) {
+						t.Fatalf("%s() got = %v, not formatted as %v", tt.name, got, tt.format)
+					}
+
+					if tt.valid != nil && !tt.valid(got) {
+						t.Fatalf("%s() got = %v, which fails its check digits", tt.name, got)
+					}
+				}
+			},
+		)
+	}
+}
+
+func Test_check_digits(t *testing.T) {
+	t.Parallel()
+
+	// real numbers, published as examples by the issuing authorities and banks
+	if got := digitString(append([]int{1, 2, 3, 4, 5, 6, 7, 8}, nifCheckDigit([]int{1, 2, 3, 4, 5, 6, 7, 8}))); got != "123456789" {
+		t.Errorf("nifCheckDigit() got = %v", got)
+	}
+
+	if got := nibCheckDigits("0002012312345678901"); got != "54" {
+		t.Errorf("nibCheckDigits() got = %v", got)
+	}
+
+	if got := ibanCheckDigits("PT", "000201231234567890154"); got != "50" {
+		t.Errorf("ibanCheckDigits() got = %v", got)
+	}
+
+	if got := ibanCheckDigits("GB", "WEST12345698765432"); got != "82" {
+		t.Errorf("ibanCheckDigits() got = %v", got)
+	}
+}
+
+func Test_service_locale(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		service Service
+		request string
+		want    *regexp.Regexp
+		wantErr bool
+	}{
+		{"configured locale", NewService(WithLocale("pt")), "faker.Locale().NIF()", regexp.MustCompile(`^\d{9}$`), false},
+		{"locale of the rule", NewService(), "faker.Locale(pt).NISS()", regexp.MustCompile(`^\d{11}$`), false},
+		{"quoted locale", NewService(), "faker.Locale(\"PT\").PostalCode()", regexp.MustCompile(`^\d{4}-\d{3}$`), false},
+		{"rule over configured", NewService(WithLocale("xx")), "faker.Locale(pt).City()", regexp.MustCompile(`.`), false},
+		{"no locale", NewService(), "faker.Locale().NIF()", nil, true},
+		{"unknown locale", NewService(), "faker.Locale(xx).NIF()", nil, true},
+		{"unknown generator", NewService(WithLocale("pt")), "faker.Locale().SSN()", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := tt.service.ReplaceStringWithFakerWhenRequested(tt.request)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("ReplaceStringWithFakerWhenRequested() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if err == nil && !tt.want.MatchString(got) {
+					t.Errorf("ReplaceStringWithFakerWhenRequested() got = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+
+	// seeded and deterministic services keep their locale for every column
+	seeded := func() string {
+		got, err := NewSeededService(42, WithLocale("pt")).ForColumn("users", "nif").
+			ReplaceStringWithFakerWhenRequested("faker.Locale().NIF()")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return got
+	}
+	if first, again := seeded(), seeded(); first != again {
+		t.Errorf("ForColumn() got = %v and %v with the same seed", first, again)
+	}
+
+	deterministic := NewDeterministicService([]byte("secret"), WithLocale("pt"))
+	first, _ := deterministic.ReplaceStringWithFakerFor("faker.Locale().IBAN()", []byte("original"))
+	again, _ := deterministic.ReplaceStringWithFakerFor("faker.Locale().IBAN()", []byte("original"))
+	if first != again || !validIBAN(first) {
+		t.Errorf("ReplaceStringWithFakerFor() got = %v and %v for the same value", first, again)
+	}
+}
+
+func TestValidateLocale(t *testing.T) {
+	t.Parallel()
+
+	for _, code := range []string{"", "pt", "PT"} {
+		if err := ValidateLocale(code); err != nil {
+			t.Errorf("ValidateLocale(%q) error = %v", code, err)
+		}
+	}
+
+	if err := ValidateLocale("pt-BR"); err == nil || err.Error() != `unsupported locale "pt-BR", use one of pt` {
+		t.Errorf("ValidateLocale() error = %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package generator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// hmacHash replaces the value with the hex encoded HMAC-SHA256 of its bytes with key, which unlike
+// a salted hash cannot be recomputed for a guessed value without knowing the key
+func hmacHash(args []stepArg) (Step, error) {
+	bound, err := bindArgs(args, "key")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = requireArgs(bound, "key"); err != nil {
+		return nil, err
+	}
+
+	key := []byte(bound["key"])
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		if value.Null {
+			return value, true, nil
+		}
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write(value.Bytes)
+
+		return Value{Bytes: []byte(hex.EncodeToString(mac.Sum(nil))), Type: "VARCHAR"}, true, nil
+	}, nil
+}
+
+// mask replaces every character of the value with char, but the first keep_first and the last keep_last
+func mask(args []stepArg) (Step, error) {
+	bound, err := bindArgs(args, "keep_last", "keep_first", "char")
+	if err != nil {
+		return nil, err
+	}
+
+	keepLast, err := intArg(bound, "keep_last", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	keepFirst, err := intArg(bound, "keep_first", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	char, err := charArg(bound)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		if value.Null {
+			return value, true, nil
+		}
+
+		runes := []rune(string(value.Bytes))
+		for i := keepFirst; i < len(runes)-keepLast; i++ {
+			runes[i] = char
+		}
+
+		// masked numbers are no longer numbers
+		value.Bytes = []byte(string(runes))
+		value.Type = "VARCHAR"
+
+		return value, true, nil
+	}, nil
+}
+
+// redact replaces the value with length times char, so not even its length is left
+func redact(args []stepArg) (Step, error) {
+	bound, err := bindArgs(args, "length", "char")
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := intArg(bound, "length", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	char, err := charArg(bound)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := []byte(strings.Repeat(string(char), length))
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		if value.Null {
+			return value, true, nil
+		}
+
+		return Value{Bytes: redacted, Type: "VARCHAR"}, true, nil
+	}, nil
+}
+
+// charArg returns the single character masks are written with, * by default
+func charArg(args map[string]string) (rune, error) {
+	char, ok := args["char"]
+	if !ok {
+		return '*', nil
+	}
+
+	if utf8.RuneCountInString(char) != 1 {
+		return 0, fmt.Errorf("char %q is not a single character", char)
+	}
+
+	r, _ := utf8.DecodeRuneInString(char)
+
+	return r, nil
+}
+
+// truncate keeps the first length characters of the value
+func truncate(args []stepArg) (Step, error) {
+	return firstCharacters(args, "length")
+}
+
+// setNull replaces the value with NULL
+func setNull(args []stepArg) (Step, error) {
+	if _, err := bindArgs(args); err != nil {
+		return nil, err
+	}
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		return Value{Null: true, Type: value.Type}, true, nil
+	}, nil
+}
+
+// setDefault replaces the value with the default value of the column
+func setDefault(args []stepArg) (Step, error) {
+	if _, err := bindArgs(args); err != nil {
+		return nil, err
+	}
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		return Value{Default: true, Type: value.Type}, true, nil
+	}, nil
+}
+
+// random replaces the value with a random one between min and max, both included. They are either
+// both numbers, written with as many decimals as the most precise of them, or both dates or datetimes
+func random(args []stepArg) (Step, error) {
+	bound, err := bindArgs(args, "min", "max")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = requireArgs(bound, "min", "max"); err != nil {
+		return nil, err
+	}
+
+	minimum, maximum := bound["min"], bound["max"]
+
+	if generate, ok := randomTimeBetween(minimum, maximum); ok {
+		return randomStep(generate, "VARCHAR"), nil
+	}
+
+	generate, err := randomNumberBetween(minimum, maximum)
+	if err != nil {
+		return nil, err
+	}
+
+	return randomStep(generate, ""), nil
+}
+
+// randomStep replaces values with the ones of generate, of type valueType when it is set. Random numbers
+// keep the type of the value, as they are written with digits only, but dates are no numbers
+func randomStep(generate func(f faker.Faker) string, valueType string) Step {
+	return func(value Value, f faker.Faker) (Value, bool, error) {
+		if value.Null {
+			return value, true, nil
+		}
+
+		value.Bytes = []byte(generate(f))
+		if valueType != "" {
+			value.Type = valueType
+		}
+
+		return value, true, nil
+	}
+}
+
+// randomTimeBetween generates times between minimum and maximum, written with their layout
+func randomTimeBetween(minimum, maximum string) (func(f faker.Faker) string, bool) {
+	for _, layout := range []string{dateLayout, dateTimeLayout} {
+		from, err := time.Parse(layout, minimum)
+		if err != nil {
+			continue
+		}
+
+		to, err := time.Parse(layout, maximum)
+		if err != nil {
+			continue
+		}
+
+		// dates are drawn by day, datetimes by second
+		unit := int64(1)
+		if layout == dateLayout {
+			unit = 24 * 60 * 60
+		}
+
+		return func(f faker.Faker) string {
+			n := f.Int64Between(from.Unix()/unit, to.Unix()/unit)
+			return time.Unix(n*unit, 0).UTC().Format(layout)
+		}, true
+	}
+
+	return nil, false
+}
+
+// randomNumberBetween generates numbers between minimum and maximum, with the decimals of the most precise
+func randomNumberBetween(minimum, maximum string) (func(f faker.Faker) string, error) {
+	decimals := max(decimalsOf(minimum), decimalsOf(maximum))
+	scale := math.Pow10(decimals)
+
+	from, err := strconv.ParseFloat(minimum, 64)
+	if err != nil {
+		return nil, fmt.Errorf("min %s is neither a number nor a date", minimum)
+	}
+
+	to, err := strconv.ParseFloat(maximum, 64)
+	if err != nil {
+		return nil, fmt.Errorf("max %s is neither a number nor a date", maximum)
+	}
+
+	low, high := int64(math.Round(from*scale)), int64(math.Round(to*scale))
+
+	return func(f faker.Faker) string {
+		return strconv.FormatFloat(float64(f.Int64Between(low, high))/scale, 'f', decimals, 64)
+	}, nil
+}
+
+func decimalsOf(number string) int {
+	if dot := strings.Index(number, "."); dot >= 0 {
+		return len(number) - dot - 1
+	}
+
+	return 0
+}
@@ -0,0 +1,257 @@
+package generator
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_masking_steps(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		rule    string
+		value   Value
+		want    Value
+		wantErr bool
+	}{
+		{
+			"masks all but the last characters",
+			"go:mask(keep_last=4)",
+			Value{Bytes: []byte("4111111111111111"), Type: "VARCHAR"},
+			Value{Bytes: []byte("************1111"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"masks numbers into strings",
+			"go:mask(keep_last=4)",
+			Value{Bytes: []byte("4111111111111111"), Type: "BIGINT"},
+			Value{Bytes: []byte("************1111"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"masks with another character, keeping both ends",
+			"go:mask(2, keep_first=1, char='#')",
+			Value{Bytes: []byte("joão.silva"), Type: "VARCHAR"},
+			Value{Bytes: []byte("j#######va"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"masks with a quoted separator",
+			"go:mask(char=',')|mask(char='|', keep_first=2)",
+			Value{Bytes: []byte("abcd"), Type: "VARCHAR"},
+			Value{Bytes: []byte(",,||"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"keeps values shorter than what is kept",
+			"go:mask(keep_last=4)",
+			Value{Bytes: []byte("123"), Type: "VARCHAR"},
+			Value{Bytes: []byte("123"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"redacts to a fixed length",
+			"go:redact",
+			Value{Bytes: []byte("a very long secret"), Type: "TEXT"},
+			Value{Bytes: []byte("********"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"redacts to a given length and character",
+			"go:redact(3, 'x')",
+			Value{Bytes: []byte("s"), Type: "INT"},
+			Value{Bytes: []byte("xxx"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"hashes with a salt",
+			"go:hash(salt='pepper')",
+			Value{Bytes: []byte("jane@example.com"), Type: "VARCHAR"},
+			Value{Bytes: []byte("2daebb07b6fe2686ef59cd504c8b776ad69262a4e97fbc978e5d7851bdc6fe15"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"hashes with an hmac",
+			"go:hmac('secret')",
+			Value{Bytes: []byte("jane@example.com"), Type: "VARCHAR"},
+			Value{Bytes: []byte("fb817989d942e7ffb3d4b8b204f7abca29f4c25c3fa46574da84c50f30d07513"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"truncates",
+			"go:truncate(length=3)",
+			Value{Bytes: []byte("Lisboa"), Type: "VARCHAR"},
+			Value{Bytes: []byte("Lis"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"truncates decimals before their digits into strings",
+			"go:truncate(2)",
+			Value{Bytes: []byte("-.5"), Type: "DECIMAL"},
+			Value{Bytes: []byte("-."), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"truncates decimals into numbers",
+			"go:truncate(4)",
+			Value{Bytes: []byte("12.345"), Type: "DOUBLE"},
+			Value{Bytes: []byte("12.3"), Type: "DOUBLE"},
+			false,
+		},
+		{
+			"sets to null",
+			"go:null",
+			Value{Bytes: []byte("Lisboa"), Type: "VARCHAR"},
+			Value{Null: true, Type: "VARCHAR"},
+			false,
+		},
+		{
+			"sets to default",
+			"go:default",
+			Value{Null: true, Type: "DATETIME"},
+			Value{Default: true, Type: "DATETIME"},
+			false,
+		},
+		{
+			"keeps null values",
+			"go:mask|redact|hash|hmac(key=k)|truncate(1)|random(1, 2)",
+			Value{Null: true, Type: "VARCHAR"},
+			Value{Null: true, Type: "VARCHAR"},
+			false,
+		},
+		{"hmac without a key", "go:hmac", Value{}, Value{}, true},
+		{"mask with a long char", "go:mask(char='**')", Value{}, Value{}, true},
+		{"mask with a negative length", "go:mask(-1)", Value{}, Value{}, true},
+		{"unknown argument", "go:mask(keep_middle=1)", Value{}, Value{}, true},
+		{"argument given twice", "go:mask(1, keep_last=2)", Value{}, Value{}, true},
+		{"positional after named", "go:mask(keep_first=1, 2)", Value{}, Value{}, true},
+		{"too many arguments", "go:truncate(1, 2)", Value{}, Value{}, true},
+		{"random without max", "go:random(1)", Value{}, Value{}, true},
+		{"random between words", "go:random(one, two)", Value{}, Value{}, true},
+		{"null with arguments", "go:null(1)", Value{}, Value{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				transform, err := ParseTransform(tt.rule)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("ParseTransform() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if err != nil {
+					return
+				}
+
+				got, err := transform.Apply(tt.value)
+				if err != nil {
+					t.Errorf("Apply() error = %v", err)
+					return
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Apply() got = %s, want %s", got.Bytes, tt.want.Bytes)
+				}
+			},
+		)
+	}
+}
+
+func Test_random_step(t *testing.T) {
+	t.Parallel()
+
+	draw := func(rule string) []string {
+		transform, err := ParseTransform(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values := make([]string, 0, 50)
+		for i := 0; i < 50; i++ {
+			v, err := transform.Apply(Value{Bytes: []byte("original"), Type: "VARCHAR"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values = append(values, string(v.Bytes))
+		}
+
+		return values
+	}
+
+	for _, v := range draw("go:random(-5, 5)") {
+		if n, err := strconv.Atoi(v); err != nil || n < -5 || n > 5 {
+			t.Errorf("random(-5, 5) got = %v", v)
+		}
+	}
+
+	for _, v := range draw("go:random(min=0.5, max=2.25)") {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0.5 || n > 2.25 || len(v) != 4 {
+			t.Errorf("random(0.5, 2.25) got = %v", v)
+		}
+	}
+
+	// dates replacing numbers are quoted
+	dates, err := ParseTransform("go:random('1960-01-01', '1960-12-31')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := dates.Apply(Value{Bytes: []byte("-7"), Type: "INT"}); v.Type != "VARCHAR" {
+		t.Errorf("random between dates got type = %v", v.Type)
+	}
+
+	// random numbers are written with digits only, so they stay numbers
+	numbers, _ := ParseTransform("go:random(-1.5, 1.5)")
+	if v, _ := numbers.Apply(Value{Bytes: []byte("-7"), Type: "INT"}); v.Type != "INT" {
+		t.Errorf("random between numbers got type = %v", v.Type)
+	}
+
+	for _, v := range draw("go:random('1960-01-01', '1960-12-31')") {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil || d.Year() != 1960 {
+			t.Errorf("random between dates got = %v", v)
+		}
+	}
+
+	for _, v := range draw("go:random('2024-01-01 08:00:00', '2024-01-01 18:00:00')") {
+		d, err := time.Parse(dateTimeLayout, v)
+		if err != nil || d.Hour() < 8 || d.Hour() > 18 {
+			t.Errorf("random between datetimes got = %v", v)
+		}
+	}
+}
+
+func Test_service_Transform(t *testing.T) {
+	t.Parallel()
+
+	apply := func(s Service, original string) string {
+		transform, err := s.Transform("go:random(1, 1000000)")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := transform.Apply(Value{Bytes: []byte(original), Type: "INT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(v.Bytes)
+	}
+
+	// deterministic services draw the same random value for the same original value
+	s := NewDeterministicService([]byte("secret"))
+	if first, again := apply(s, "42"), apply(s, "42"); first != again {
+		t.Errorf("Transform() got = %v and %v for the same value", first, again)
+	}
+
+	// seeded services draw the same values on every run
+	if first, again := apply(NewSeededService(42), "42"), apply(NewSeededService(42), "43"); first != again {
+		t.Errorf("Transform() got = %v and %v with the same seed", first, again)
+	}
+
+	if _, err := s.Transform("go:scramble"); err == nil {
+		t.Errorf("Transform() should have errored")
+	}
+}
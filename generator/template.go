@@ -0,0 +1,181 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+// TemplatePrefix starts the rewrite rules that are text/template templates, executed for every row,
+// such as tmpl:{{faker.Person.FirstName | lower}}.{{seq}}@example.test
+const TemplatePrefix = "tmpl:"
+
+// TemplateData is what templates are executed with, as in {{.Table}}
+type TemplateData struct {
+	Table  string
+	Column string
+	// Row is the number of the row in the dump of its table, from 1
+	Row int64
+	// Value is the original value of the column
+	Value string
+	// Attempt counts how many times the value was asked for again, as the previous one was already
+	// taken by another row of a UNIQUE key
+	Attempt int
+}
+
+// Template is a compiled template rule. It is not safe for concurrent use, as the faker
+// and row its functions see are the ones of the row being executed
+type Template struct {
+	rule     string
+	template *template.Template
+	// faker returns the faker the functions draw random values from, for an original value
+	faker  func(original []byte) faker.Faker
+	locale string
+
+	current faker.Faker
+	data    TemplateData
+}
+
+// IsTemplate tells whether rule is a template, executed in Go for every row
+func IsTemplate(rule string) bool {
+	return strings.HasPrefix(rule, TemplatePrefix)
+}
+
+// ParseTemplate compiles a rule starting with TemplatePrefix into a Template,
+// whose random values are drawn from an unseeded faker
+func ParseTemplate(rule string) (*Template, error) {
+	return parseTemplate(rule, "")
+}
+
+func parseTemplate(rule, locale string) (*Template, error) {
+	if !IsTemplate(rule) {
+		return nil, fmt.Errorf("template %q does not start with %s", rule, TemplatePrefix)
+	}
+
+	f := faker.New()
+	t := &Template{
+		rule:   rule,
+		locale: locale,
+		faker: func([]byte) faker.Faker {
+			return f
+		},
+	}
+
+	var err error
+	t.template, err = template.New("tmpl").
+		Option("missingkey=error").
+		Funcs(t.functions()).
+		Parse(strings.TrimPrefix(rule, TemplatePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", rule, err)
+	}
+
+	return t, nil
+}
+
+// functions are the functions templates can call besides the builtin ones: faker returns the
+// faker of the row, as in {{faker.Person.Name}}, locale the generators of a locale, as in
+// {{locale.NIF}} or {{(locale "pt").NIF}}, and seq the number of the row
+func (t *Template) functions() template.FuncMap {
+	return template.FuncMap{
+		"faker": func() *faker.Faker {
+			return &t.current
+		},
+		"locale": func(code ...string) (LocaleGenerator, error) {
+			if len(code) > 1 {
+				return nil, fmt.Errorf("locale takes the code of a single locale and got %d", len(code))
+			}
+
+			locale := t.locale
+			if len(code) == 1 {
+				locale = code[0]
+			}
+
+			factory, err := localeFactory(locale)
+			if err != nil {
+				return nil, err
+			}
+
+			return factory(t.current), nil
+		},
+		"seq": func() int64 {
+			return t.data.Row
+		},
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"trim":  strings.TrimSpace,
+		"slug":  slug,
+	}
+}
+
+// Execute renders the template for a row, the faker of the row being seeded from its original value
+// when the template was compiled by a deterministic Service
+func (t *Template) Execute(data TemplateData) (string, error) {
+	t.current = t.faker(RetryOriginal([]byte(data.Value), data.Attempt))
+	t.data = data
+
+	var b bytes.Buffer
+	if err := t.template.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("template %q: %w", t.rule, err)
+	}
+
+	return b.String(), nil
+}
+
+// slug lowers s and joins its words with dashes, dropping accents and anything but letters and digits,
+// so João Gonçalves becomes joao-goncalves
+func slug(s string) string {
+	var b strings.Builder
+
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if folded, ok := accents[r]; ok {
+			r = folded
+		}
+
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+
+			b.WriteRune(r)
+			dash = false
+
+			continue
+		}
+
+		dash = true
+	}
+
+	return b.String()
+}
+
+// FoldAccents lowers s and drops the accents of its latin letters, so João becomes joao
+func FoldAccents(s string) string {
+	return strings.Map(
+		func(r rune) rune {
+			if folded, ok := accents[r]; ok {
+				return folded
+			}
+
+			return r
+		},
+		strings.ToLower(s),
+	)
+}
+
+// accents maps the accented lower case letters of latin languages to the ones without their accents
+var accents = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a',
+	'ç': 'c',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ñ': 'n',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y',
+}
@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	t.Parallel()
+
+	data := TemplateData{Table: "users", Column: "email", Row: 7, Value: "João Gonçalves"}
+
+	tests := []struct {
+		name    string
+		rule    string
+		want    *regexp.Regexp
+		wantErr string
+	}{
+		{"literals and row number", "tmpl:TEST-{{seq}}", regexp.MustCompile(`^TEST-7$`), ""},
+		{"row data", "tmpl:{{.Table}}.{{.Column}}#{{.Row}}", regexp.MustCompile(`^users\.email#7$`), ""},
+		{"helpers", "tmpl:{{slug .Value}} {{upper .Value}} {{trim \"  x \"}}", regexp.MustCompile(`^joao-goncalves JOÃO GONÇALVES x$`), ""},
+		{
+			"faker chains",
+			"tmpl:{{faker.Person.FirstName | lower}}.{{faker.Person.LastName | lower}}@example.test",
+			regexp.MustCompile(`^[^@A-Z]+\.[^@A-Z]+@example\.test$`),
+			"",
+		},
+		{"faker arguments", "tmpl:{{faker.Lorem.Sentence 3}}", regexp.MustCompile(`^(\w+ ){2}\w+\.$`), ""},
+		{"given locale", `tmpl:{{(locale "pt").PostalCode}}`, regexp.MustCompile(`^\d{4}-\d{3}$`), ""},
+		{"no locale", "tmpl:{{locale.NIF}}", nil, "no locale is configured"},
+		{"unknown locale", `tmpl:{{(locale "xx").Name}}`, nil, `unsupported locale "xx"`},
+		{"unknown function", "tmpl:{{shout .Value}}", nil, `function "shout" not defined`},
+		{"unknown field", "tmpl:{{.Email}}", nil, "can't evaluate field Email"},
+		{"not closed", "tmpl:{{seq", nil, "unclosed action"},
+		{"without prefix", "{{seq}}", nil, "does not start with tmpl:"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tmpl, err := ParseTemplate(tt.rule)
+				if err == nil {
+					var got string
+					if got, err = tmpl.Execute(data); err == nil && !tt.want.MatchString(got) {
+						t.Errorf("Execute() got = %v, want %v", got, tt.want)
+					}
+				}
+
+				if tt.wantErr == "" && err != nil {
+					t.Errorf("template error = %v", err)
+				}
+
+				if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+					t.Errorf("template error = %v, want %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func Test_service_Template(t *testing.T) {
+	t.Parallel()
+
+	rule := "tmpl:{{faker.Person.Name}} {{locale.NIF}}"
+	data := TemplateData{Table: "users", Column: "name", Row: 1, Value: "Jane"}
+
+	// deterministic services draw the values of the same original value from the same seed
+	deterministic, err := NewDeterministicService([]byte("key"), WithLocale("pt")).Template(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := deterministic.Execute(data)
+	data.Row = 2
+	again, _ := deterministic.Execute(data)
+	if first != again {
+		t.Errorf("Execute() got = %v and %v for the same value", first, again)
+	}
+
+	data.Value = "John"
+	other, _ := deterministic.Execute(data)
+	if other == first {
+		t.Errorf("Execute() got = %v for different values", other)
+	}
+
+	// values taken by another row of a UNIQUE key are drawn again from another seed
+	data.Attempt = 1
+	if retried, _ := deterministic.Execute(data); retried == other {
+		t.Errorf("Execute() got = %v on another attempt", retried)
+	}
+
+	// seeded services generate the same values on every run
+	seeded := func() string {
+		tmpl, _ := NewSeededService(42, WithLocale("pt")).ForColumn("users", "name").Template(rule)
+		got, _ := tmpl.Execute(data)
+		return got
+	}
+
+	if a, b := seeded(), seeded(); a != b {
+		t.Errorf("Execute() got = %v and %v with the same seed", a, b)
+	}
+}
+
+func TestFoldAccents(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"João Gonçalves": "joao goncalves",
+		"JOSÉ":           "jose",
+		"Ñandú 42":       "nandu 42",
+		"O'Neil":         "o'neil",
+	}
+	for s, want := range tests {
+		if got := FoldAccents(s); got != want {
+			t.Errorf("FoldAccents(%q) got = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func Test_slug(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"João Gonçalves":        "joao-goncalves",
+		"  O'Neil & Sons, Ltd ": "o-neil-sons-ltd",
+		"Ñandú 42":              "nandu-42",
+		"---":                   "",
+	}
+	for s, want := range tests {
+		if got := slug(s); got != want {
+			t.Errorf("slug(%q) got = %v, want %v", s, got, want)
+		}
+	}
+}
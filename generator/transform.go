@@ -0,0 +1,348 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jaswdr/faker/v2"
+)
+
+// TransformPrefix starts the rewrite rules that are applied in Go, to the original value of the column
+const TransformPrefix = "go:"
+
+// Value is a column value handed to a transform
+type Value struct {
+	Bytes []byte
+	Null  bool
+	// Default asks for the default value of the column instead of any value
+	Default bool
+	// Type is the database type of the value, as reported by the driver, such as VARCHAR or INT.
+	// Transforms changing the kind of a value, such as hash, set it to the type of their result
+	Type string
+	// Attempt counts how many times the value was asked for again, as the previous one was already taken
+	// by another row of a UNIQUE key. Random values are drawn anew on every attempt, see RetryOriginal
+	Attempt int
+}
+
+// Step is a single transform of a pipeline, it returns the value handed to the next step
+// and false when the pipeline ends with that value. Random values are drawn from f
+type Step func(value Value, f faker.Faker) (Value, bool, error)
+
+// Transform is a pipeline of steps, separated by "|" in its rule, such as
+// go:keep_if_empty|keep_first(1)
+type Transform struct {
+	rule  string
+	steps []Step
+	// faker returns the faker the steps draw random values from, for an original value
+	faker func(original []byte) faker.Faker
+}
+
+// stepFactory builds a step out of the arguments of its rule
+type stepFactory func(args []stepArg) (Step, error)
+
+// stepArg is an argument of a step, name is only set for the ones passed by name, as in mask(keep_last=4)
+type stepArg struct {
+	name  string
+	value string
+}
+
+var steps = map[string]stepFactory{
+	"keep_if_empty": keepIfEmpty,
+	"keep_first":    keepFirst,
+	"hash":          hash,
+	"hmac":          hmacHash,
+	"mask":          mask,
+	"redact":        redact,
+	"truncate":      truncate,
+	"null":          setNull,
+	"default":       setDefault,
+	"random":        random,
+}
+
+// IsTransform tells whether rule is applied in Go rather than in the SELECT
+func IsTransform(rule string) bool {
+	return strings.HasPrefix(rule, TransformPrefix)
+}
+
+// ParseTransform compiles a rule starting with TransformPrefix into a Transform,
+// whose random values are drawn from an unseeded faker
+func ParseTransform(rule string) (*Transform, error) {
+	if !IsTransform(rule) {
+		return nil, fmt.Errorf("transform %q does not start with %s", rule, TransformPrefix)
+	}
+
+	f := faker.New()
+	t := &Transform{
+		rule: rule,
+		faker: func([]byte) faker.Faker {
+			return f
+		},
+	}
+
+	for _, call := range splitOutsideQuotes(strings.TrimPrefix(rule, TransformPrefix), '|') {
+		name, args, err := parseStepCall(strings.TrimSpace(call))
+		if err != nil {
+			return nil, fmt.Errorf("transform %q: %w", rule, err)
+		}
+
+		factory, ok := steps[name]
+		if !ok {
+			return nil, fmt.Errorf("transform %q: unknown step %s", rule, name)
+		}
+
+		step, err := factory(args)
+		if err != nil {
+			return nil, fmt.Errorf("transform %q: %s: %w", rule, name, err)
+		}
+
+		t.steps = append(t.steps, step)
+	}
+
+	return t, nil
+}
+
+// Apply runs value through every step of the pipeline
+func (t *Transform) Apply(value Value) (Value, error) {
+	f := t.faker(RetryOriginal(value.Bytes, value.Attempt))
+
+	for _, step := range t.steps {
+		next, ok, err := step(value, f)
+		if err != nil {
+			return value, fmt.Errorf("transform %q: %w", t.rule, err)
+		}
+
+		value = next
+		if !ok {
+			break
+		}
+	}
+
+	return value, nil
+}
+
+// parseStepCall splits a step such as mask(4, char='#') into its name and arguments,
+// which may be quoted with single or double quotes
+func parseStepCall(call string) (string, []stepArg, error) {
+	open := strings.Index(call, "(")
+	if open < 0 {
+		if call == "" {
+			return "", nil, errors.New("empty step")
+		}
+
+		return call, nil, nil
+	}
+
+	if !strings.HasSuffix(call, ")") {
+		return "", nil, fmt.Errorf("step %s is missing its closing parenthesis", call)
+	}
+
+	name := strings.TrimSpace(call[:open])
+	inner := strings.TrimSpace(call[open+1 : len(call)-1])
+	if inner == "" {
+		return name, nil, nil
+	}
+
+	var args []stepArg
+	for _, raw := range splitOutsideQuotes(inner, ',') {
+		var arg stepArg
+
+		raw = strings.TrimSpace(raw)
+		if eq := strings.Index(raw, "="); eq > 0 && isIdentifier(strings.TrimSpace(raw[:eq])) {
+			arg.name = strings.TrimSpace(raw[:eq])
+			raw = strings.TrimSpace(raw[eq+1:])
+		}
+
+		if len(raw) >= 2 && (raw[0] == '\'' || raw[0] == '"') && raw[len(raw)-1] == raw[0] {
+			raw = raw[1 : len(raw)-1]
+		}
+
+		arg.value = raw
+		args = append(args, arg)
+	}
+
+	return name, args, nil
+}
+
+// splitOutsideQuotes splits s around every sep that is not quoted
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bindArgs binds the arguments of a step to its parameters, given in order. Arguments are passed
+// by position, by name, or by position first and by name after. Missing ones are left out
+func bindArgs(args []stepArg, params ...string) (map[string]string, error) {
+	bound := make(map[string]string)
+
+	for i, arg := range args {
+		name := arg.name
+		if name == "" {
+			if i > 0 && args[i-1].name != "" {
+				return nil, errors.New("positional arguments must come before named ones")
+			}
+
+			if i >= len(params) {
+				return nil, fmt.Errorf("takes at most %d arguments and got %d", len(params), len(args))
+			}
+
+			name = params[i]
+		}
+
+		known := false
+		for _, param := range params {
+			known = known || param == name
+		}
+
+		if !known {
+			return nil, fmt.Errorf("unknown argument %s", name)
+		}
+
+		if _, ok := bound[name]; ok {
+			return nil, fmt.Errorf("argument %s is given twice", name)
+		}
+
+		bound[name] = arg.value
+	}
+
+	return bound, nil
+}
+
+// intArg returns the argument called name as a non negative number, or fallback when it is missing
+func intArg(args map[string]string, name string, fallback int) (int, error) {
+	v, ok := args[name]
+	if !ok {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s %s is not a non negative number", name, v)
+	}
+
+	return n, nil
+}
+
+// requireArgs fails when any of names is missing from args
+func requireArgs(args map[string]string, names ...string) error {
+	for _, name := range names {
+		if _, ok := args[name]; !ok {
+			return fmt.Errorf("argument %s is required", name)
+		}
+	}
+
+	return nil
+}
+
+// keepIfEmpty ends the pipeline with empty and NULL values, which are kept as they are
+func keepIfEmpty(args []stepArg) (Step, error) {
+	if _, err := bindArgs(args); err != nil {
+		return nil, err
+	}
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		return value, !value.Null && len(value.Bytes) > 0, nil
+	}, nil
+}
+
+// keepFirst keeps the first n characters of the value
+func keepFirst(args []stepArg) (Step, error) {
+	return firstCharacters(args, "n")
+}
+
+func firstCharacters(args []stepArg, param string) (Step, error) {
+	bound, err := bindArgs(args, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = requireArgs(bound, param); err != nil {
+		return nil, err
+	}
+
+	n, err := intArg(bound, param, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		if value.Null {
+			return value, true, nil
+		}
+
+		if runes := []rune(string(value.Bytes)); len(runes) > n {
+			value.Bytes = []byte(string(runes[:n]))
+
+			// the first characters of a number, such as the - of -5, are not always one
+			if !isNumber(value.Bytes) {
+				value.Type = "VARCHAR"
+			}
+		}
+
+		return value, true, nil
+	}, nil
+}
+
+// numberPattern matches the numeric literals of SQL, which are written unquoted
+var numberPattern = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+func isNumber(b []byte) bool {
+	return numberPattern.Match(b)
+}
+
+// hash replaces the value with the hex encoded SHA-256 of its bytes, preceded by salt when one is given
+func hash(args []stepArg) (Step, error) {
+	bound, err := bindArgs(args, "salt")
+	if err != nil {
+		return nil, err
+	}
+
+	salt := []byte(bound["salt"])
+
+	return func(value Value, _ faker.Faker) (Value, bool, error) {
+		if value.Null {
+			return value, true, nil
+		}
+
+		h := sha256.New()
+		h.Write(salt)
+		h.Write(value.Bytes)
+
+		return Value{Bytes: []byte(hex.EncodeToString(h.Sum(nil))), Type: "VARCHAR"}, true, nil
+	}, nil
+}
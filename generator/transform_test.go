@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTransform(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		rule    string
+		value   Value
+		want    Value
+		wantErr bool
+	}{
+		{
+			"keeps the first letter",
+			"go:keep_first(1)",
+			Value{Bytes: []byte("Élise"), Type: "VARCHAR"},
+			Value{Bytes: []byte("É"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"keeps the sign of negative numbers as a string",
+			"go:keep_first(1)",
+			Value{Bytes: []byte("-5"), Type: "INT"},
+			Value{Bytes: []byte("-"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"keeps the first digits of numbers as numbers",
+			"go:keep_first(3)",
+			Value{Bytes: []byte("-5.25"), Type: "DECIMAL"},
+			Value{Bytes: []byte("-5."), Type: "DECIMAL"},
+			false,
+		},
+		{
+			"keeps shorter values whole",
+			"go:keep_first(10)",
+			Value{Bytes: []byte("Jane"), Type: "VARCHAR"},
+			Value{Bytes: []byte("Jane"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"hashes the value",
+			"go:hash",
+			Value{Bytes: []byte("jane@example.com"), Type: "VARCHAR"},
+			Value{Bytes: []byte("8c87b489ce35cf2e2f39f80e282cb2e804932a56a213983eeeb428407d43b52d"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"hashes numbers into strings",
+			"go:hash()",
+			Value{Bytes: []byte("42"), Type: "INT"},
+			Value{Bytes: []byte("73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049"), Type: "VARCHAR"},
+			false,
+		},
+		{
+			"keeps empty values",
+			"go:keep_if_empty | hash",
+			Value{Bytes: []byte{}, Type: "VARCHAR"},
+			Value{Bytes: []byte{}, Type: "VARCHAR"},
+			false,
+		},
+		{
+			"keeps null values",
+			"go:keep_if_empty|keep_first(1)",
+			Value{Null: true, Type: "VARCHAR"},
+			Value{Null: true, Type: "VARCHAR"},
+			false,
+		},
+		{
+			"carries on with other values",
+			"go:keep_if_empty|keep_first('2')",
+			Value{Bytes: []byte("Jane"), Type: "VARCHAR"},
+			Value{Bytes: []byte("Ja"), Type: "VARCHAR"},
+			false,
+		},
+		{"not a transform", "faker.Person().Name()", Value{}, Value{}, true},
+		{"unknown step", "go:keep_first(1)|scramble", Value{}, Value{}, true},
+		{"empty step", "go:hash|", Value{}, Value{}, true},
+		{"missing parenthesis", "go:keep_first(1", Value{}, Value{}, true},
+		{"missing argument", "go:keep_first", Value{}, Value{}, true},
+		{"extra argument", "go:hash(1, 2)", Value{}, Value{}, true},
+		{"not a number", "go:keep_first(one)", Value{}, Value{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				transform, err := ParseTransform(tt.rule)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("ParseTransform() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if err != nil {
+					return
+				}
+
+				got, err := transform.Apply(tt.value)
+				if err != nil {
+					t.Errorf("Apply() error = %v", err)
+					return
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Apply() got = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
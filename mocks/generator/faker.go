@@ -7,6 +7,7 @@ package mock_generator
 import (
 	reflect "reflect"
 
+	generator "github.com/doutorfinancas/go-mad/generator"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -33,6 +34,50 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// Compile mocks base method.
+func (m *MockService) Compile(request string) (generator.FakerFunc, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Compile", request)
+	ret0, _ := ret[0].(generator.FakerFunc)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Compile indicates an expected call of Compile.
+func (mr *MockServiceMockRecorder) Compile(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compile", reflect.TypeOf((*MockService)(nil).Compile), request)
+}
+
+// ForColumn mocks base method.
+func (m *MockService) ForColumn(table, column string) generator.Service {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForColumn", table, column)
+	ret0, _ := ret[0].(generator.Service)
+	return ret0
+}
+
+// ForColumn indicates an expected call of ForColumn.
+func (mr *MockServiceMockRecorder) ForColumn(table, column interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForColumn", reflect.TypeOf((*MockService)(nil).ForColumn), table, column)
+}
+
+// ReplaceStringWithFakerFor mocks base method.
+func (m *MockService) ReplaceStringWithFakerFor(request string, original []byte) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceStringWithFakerFor", request, original)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplaceStringWithFakerFor indicates an expected call of ReplaceStringWithFakerFor.
+func (mr *MockServiceMockRecorder) ReplaceStringWithFakerFor(request, original interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceStringWithFakerFor", reflect.TypeOf((*MockService)(nil).ReplaceStringWithFakerFor), request, original)
+}
+
 // ReplaceStringWithFakerWhenRequested mocks base method.
 func (m *MockService) ReplaceStringWithFakerWhenRequested(request string) (string, error) {
 	m.ctrl.T.Helper()
@@ -47,3 +92,33 @@ func (mr *MockServiceMockRecorder) ReplaceStringWithFakerWhenRequested(request i
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceStringWithFakerWhenRequested", reflect.TypeOf((*MockService)(nil).ReplaceStringWithFakerWhenRequested), request)
 }
+
+// Template mocks base method.
+func (m *MockService) Template(rule string) (*generator.Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Template", rule)
+	ret0, _ := ret[0].(*generator.Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Template indicates an expected call of Template.
+func (mr *MockServiceMockRecorder) Template(rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Template", reflect.TypeOf((*MockService)(nil).Template), rule)
+}
+
+// Transform mocks base method.
+func (m *MockService) Transform(rule string) (*generator.Transform, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transform", rule)
+	ret0, _ := ret[0].(*generator.Transform)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transform indicates an expected call of Transform.
+func (mr *MockServiceMockRecorder) Transform(rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transform", reflect.TypeOf((*MockService)(nil).Transform), rule)
+}